@@ -0,0 +1,64 @@
+package address
+
+import (
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/seithhq/crypto-finder/backend"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+const Length = 20
+
+// Deriver turns private keys into Ethereum addresses without allocating.
+// It keeps its Keccak state and scratch buffers between calls, so every
+// worker needs its own.
+type Deriver struct {
+	backend backend.Backend
+	hasher  crypto.KeccakState
+	pub     [64]byte
+	pubs    [][64]byte
+	sum     [32]byte
+}
+
+// NewDeriver returns a Deriver computing public keys with b. b may be nil
+// when only public keys are hashed, see FromPublic.
+func NewDeriver(b backend.Backend) *Deriver {
+	return &Deriver{backend: b, hasher: crypto.NewKeccakState()}
+}
+
+// Derive writes the address of the big-endian scalar priv into out. priv
+// must already be a valid key, see key.Private.Validate.
+func (d *Deriver) Derive(priv *[32]byte, out *[Length]byte) {
+	d.backend.PublicKey(priv, &d.pub)
+	d.FromPublic(&d.pub, out)
+}
+
+// DeriveBatch writes the address of privs[i] into out[i], letting the
+// backend share work across the batch.
+func (d *Deriver) DeriveBatch(privs []key.Private, out [][Length]byte) {
+	if cap(d.pubs) < len(privs) {
+		d.pubs = make([][64]byte, len(privs))
+	}
+	pubs := d.pubs[:len(privs)]
+	d.backend.PublicKeys(privs, pubs)
+	for i := range pubs {
+		d.FromPublic(&pubs[i], &out[i])
+	}
+}
+
+// FromAffine writes the address of the public key (x, y) into out. Both
+// coordinates must be normalized.
+func (d *Deriver) FromAffine(x, y *secp256k1.FieldVal, out *[Length]byte) {
+	x.PutBytesUnchecked(d.pub[:32])
+	y.PutBytesUnchecked(d.pub[32:])
+	d.FromPublic(&d.pub, out)
+}
+
+// FromPublic writes the address of the uncompressed public key X || Y,
+// without the 0x04 prefix, into out.
+func (d *Deriver) FromPublic(pub *[64]byte, out *[Length]byte) {
+	d.hasher.Reset()
+	d.hasher.Write(pub[:])
+	d.hasher.Read(d.sum[:])
+	copy(out[:], d.sum[12:])
+}
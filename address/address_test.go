@@ -0,0 +1,97 @@
+package address
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/seithhq/crypto-finder/backend"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+func keys(t testing.TB, n int) []key.Private {
+	privs := make([]key.Private, n)
+	for i := range privs {
+		prv, err := key.Parse("0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5a6b01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		prv[30], prv[31] = byte(i>>8), byte(i)
+		privs[i] = prv
+	}
+	return privs
+}
+
+func TestDerive(t *testing.T) {
+	privs := keys(t, 16)
+	for _, be := range backend.All() {
+		t.Run(be.Name(), func(t *testing.T) {
+			d := NewDeriver(be)
+			batch := make([][Length]byte, len(privs))
+			d.DeriveBatch(privs, batch)
+			for i := range privs {
+				ecdsa, err := crypto.ToECDSA(privs[i][:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := crypto.PubkeyToAddress(ecdsa.PublicKey)
+
+				var got [Length]byte
+				d.Derive((*[32]byte)(&privs[i]), &got)
+				if got != want {
+					t.Errorf("Derive(%s) = %x, want %x", privs[i].Hex(), got, want)
+				}
+				if batch[i] != want {
+					t.Errorf("DeriveBatch(%s) = %x, want %x", privs[i].Hex(), batch[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveAllocs(t *testing.T) {
+	privs := keys(t, 64)
+	addrs := make([][Length]byte, len(privs))
+	for _, be := range backend.All() {
+		if be.Name() == "purego" {
+			continue
+		}
+		d := NewDeriver(be)
+		d.DeriveBatch(privs, addrs)
+		var addr [Length]byte
+		if n := testing.AllocsPerRun(10, func() { d.Derive((*[32]byte)(&privs[0]), &addr) }); n != 0 {
+			t.Errorf("%s: Derive allocates %v times", be.Name(), n)
+		}
+		if n := testing.AllocsPerRun(10, func() { d.DeriveBatch(privs, addrs) }); n != 0 {
+			t.Errorf("%s: DeriveBatch allocates %v times", be.Name(), n)
+		}
+	}
+}
+
+func BenchmarkDerive(b *testing.B) {
+	privs := keys(b, 256)
+	for _, be := range backend.All() {
+		b.Run(be.Name(), func(b *testing.B) {
+			d := NewDeriver(be)
+			var addr [Length]byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				d.Derive((*[32]byte)(&privs[i%len(privs)]), &addr)
+			}
+		})
+	}
+}
+
+func BenchmarkDeriveBatch(b *testing.B) {
+	privs := keys(b, 256)
+	for _, be := range backend.All() {
+		b.Run(be.Name(), func(b *testing.B) {
+			d := NewDeriver(be)
+			addrs := make([][Length]byte, len(privs))
+			b.ReportAllocs()
+			for left := b.N; left > 0; left -= len(privs) {
+				n := min(left, len(privs))
+				d.DeriveBatch(privs[:n], addrs[:n])
+			}
+		})
+	}
+}
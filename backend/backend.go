@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seithhq/crypto-finder/key"
+)
+
+// Backend is a secp256k1 implementation used to turn private keys into
+// public keys. Public keys are exchanged in their 64 byte uncompressed
+// serialization X || Y, without the 0x04 prefix, which is what Ethereum
+// addresses are hashed from.
+type Backend interface {
+	Name() string
+	// PublicKey writes the public key of priv into pub. priv must be a valid
+	// scalar, see key.Private.Validate.
+	PublicKey(priv *[32]byte, pub *[64]byte)
+	// PublicKeys derives pubs[i] from privs[i] for the whole batch.
+	PublicKeys(privs []key.Private, pubs [][64]byte)
+	// Serialize encodes pub in the SEC1 format: 33 bytes when compressed,
+	// 65 bytes with the 0x04 prefix otherwise.
+	Serialize(pub *[64]byte, compressed bool) []byte
+}
+
+var registry = map[string]Backend{}
+
+func register(b Backend) {
+	registry[b.Name()] = b
+}
+
+// All returns the backends compiled into this binary, sorted by name.
+func All() []Backend {
+	all := make([]Backend, 0, len(registry))
+	for _, b := range registry {
+		all = append(all, b)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name() < all[j].Name() })
+	return all
+}
+
+// ByName returns the named backend. "auto" picks the fastest one on this
+// machine, see Fastest.
+func ByName(name string) (Backend, error) {
+	if name == "auto" {
+		return Fastest(50 * time.Millisecond), nil
+	}
+	b, ok := registry[name]
+	if !ok {
+		names := make([]string, 0, len(registry))
+		for _, b := range All() {
+			names = append(names, b.Name())
+		}
+		return nil, fmt.Errorf("unknown backend %q, want auto or one of %s", name, strings.Join(names, ", "))
+	}
+	return b, nil
+}
+
+// Fastest times every backend for about d and returns the one with the
+// highest throughput.
+func Fastest(d time.Duration) Backend {
+	var best Backend
+	bestRate := 0.0
+	for _, b := range All() {
+		if rate := Measure(b, d); rate > bestRate {
+			best, bestRate = b, rate
+		}
+	}
+	return best
+}
+
+// Measure returns how many public keys per second b derives in batches,
+// running for about d.
+func Measure(b Backend, d time.Duration) float64 {
+	privs := make([]key.Private, 64)
+	pubs := make([][64]byte, len(privs))
+	for i := range privs {
+		privs[i][0] = 0x5c
+		privs[i][31] = byte(i + 1)
+	}
+
+	n := 0
+	start := time.Now()
+	for time.Since(start) < d {
+		b.PublicKeys(privs, pubs)
+		n += len(privs)
+		privs[0][30]++
+	}
+	return float64(n) / time.Since(start).Seconds()
+}
+
+// Verify derives public keys for a fixed set of edge-case and pseudo-random
+// scalars with every backend and reports the first disagreement.
+func Verify(n int) error {
+	privs := testScalars(n)
+	all := All()
+	want := make([][64]byte, len(privs))
+	all[0].PublicKeys(privs, want)
+
+	for _, b := range all {
+		got := make([][64]byte, len(privs))
+		b.PublicKeys(privs, got)
+		for i := range privs {
+			var single [64]byte
+			b.PublicKey((*[32]byte)(&privs[i]), &single)
+			if got[i] != want[i] || single != want[i] {
+				return fmt.Errorf("backend %s disagrees with %s on key %s", b.Name(), all[0].Name(), privs[i].Hex())
+			}
+			for _, compressed := range []bool{false, true} {
+				if string(b.Serialize(&got[i], compressed)) != string(all[0].Serialize(&want[i], compressed)) {
+					return fmt.Errorf("backend %s serializes key %s differently from %s", b.Name(), privs[i].Hex(), all[0].Name())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// testScalars returns 1, 2, N-1, N-2 and n-4 scalars from a fixed
+// xorshift sequence.
+func testScalars(n int) []key.Private {
+	orderMinus := func(d byte) key.Private {
+		k, _ := key.Parse("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+		k[key.Length-1] -= d
+		return k
+	}
+	one, two := key.Private{}, key.Private{}
+	one[key.Length-1], two[key.Length-1] = 1, 2
+	privs := []key.Private{one, two, orderMinus(1), orderMinus(2)}
+
+	state := uint64(0x9e3779b97f4a7c15)
+	for len(privs) < max(n, 4) {
+		var k key.Private
+		for i := 0; i < key.Length; i += 8 {
+			state ^= state << 13
+			state ^= state >> 7
+			state ^= state << 17
+			for j := 0; j < 8; j++ {
+				k[i+j] = byte(state >> (8 * j))
+			}
+		}
+		if k.Validate() == nil {
+			privs = append(privs, k)
+		}
+	}
+	return privs
+}
+
+// serialize is the SEC1 encoding shared by backends without their own.
+func serialize(pub *[64]byte, compressed bool) []byte {
+	if !compressed {
+		return append([]byte{0x04}, pub[:]...)
+	}
+	out := make([]byte, 33)
+	out[0] = 0x02 | pub[63]&1
+	copy(out[1:], pub[:32])
+	return out
+}
@@ -0,0 +1,79 @@
+package backend
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/seithhq/crypto-finder/key"
+)
+
+func scalars(t testing.TB) []key.Private {
+	one := key.Private{}
+	one[key.Length-1] = 1
+	orderMinusOne, err := key.Parse("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privs := []key.Private{one, orderMinusOne}
+	for len(privs) < 64 {
+		var k key.Private
+		rand.Read(k[:])
+		if k.Validate() == nil {
+			privs = append(privs, k)
+		}
+	}
+	return privs
+}
+
+func TestBackendsAgree(t *testing.T) {
+	privs := scalars(t)
+	ref := registry["purego"]
+	want := make([][64]byte, len(privs))
+	ref.PublicKeys(privs, want)
+
+	// G and -G share X and differ in Y.
+	if [32]byte(want[0][:32]) != [32]byte(want[1][:32]) || want[0] == want[1] {
+		t.Fatalf("1 and n-1 give %x and %x, want G and -G", want[0], want[1])
+	}
+
+	for _, b := range All() {
+		t.Run(b.Name(), func(t *testing.T) {
+			got := make([][64]byte, len(privs))
+			b.PublicKeys(privs, got)
+			for i := range privs {
+				var single [64]byte
+				b.PublicKey((*[32]byte)(&privs[i]), &single)
+				if got[i] != want[i] {
+					t.Errorf("PublicKeys(%s) = %x, want %x", privs[i].Hex(), got[i], want[i])
+				}
+				if single != want[i] {
+					t.Errorf("PublicKey(%s) = %x, want %x", privs[i].Hex(), single, want[i])
+				}
+				for _, compressed := range []bool{false, true} {
+					if g, w := b.Serialize(&got[i], compressed), ref.Serialize(&want[i], compressed); string(g) != string(w) {
+						t.Errorf("Serialize(%s, %t) = %x, want %x", privs[i].Hex(), compressed, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if err := Verify(256); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkPublicKeys(b *testing.B) {
+	privs := scalars(b)
+	pubs := make([][64]byte, len(privs))
+	for _, be := range All() {
+		b.Run(be.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i += len(privs) {
+				be.PublicKeys(privs, pubs)
+			}
+		})
+	}
+}
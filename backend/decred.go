@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+func init() {
+	register(decred{})
+}
+
+// decred is the pure Go dcrd implementation. Batches share a single field
+// inversion when converting to affine coordinates.
+type decred struct{}
+
+func (decred) Name() string {
+	return "decred"
+}
+
+func (decred) PublicKey(priv *[32]byte, pub *[64]byte) {
+	var s secp256k1.ModNScalar
+	var p secp256k1.JacobianPoint
+	s.SetBytes(priv)
+	secp256k1.ScalarBaseMultNonConst(&s, &p)
+	p.ToAffine()
+	p.X.PutBytesUnchecked(pub[:32])
+	p.Y.PutBytesUnchecked(pub[32:])
+}
+
+// batchScratch is the working space of a batch, pooled so that deriving
+// does not allocate.
+type batchScratch struct {
+	points []secp256k1.JacobianPoint
+	acc    []secp256k1.FieldVal
+}
+
+var scratchPool = sync.Pool{New: func() any { return new(batchScratch) }}
+
+func (decred) PublicKeys(privs []key.Private, pubs [][64]byte) {
+	scratch := scratchPool.Get().(*batchScratch)
+	defer scratchPool.Put(scratch)
+	if cap(scratch.points) < len(privs) {
+		scratch.points = make([]secp256k1.JacobianPoint, len(privs))
+		scratch.acc = make([]secp256k1.FieldVal, len(privs))
+	}
+	points := scratch.points[:len(privs)]
+
+	var s secp256k1.ModNScalar
+	for i := range privs {
+		s.SetBytes((*[32]byte)(&privs[i]))
+		secp256k1.ScalarBaseMultNonConst(&s, &points[i])
+	}
+	ToAffine(points, scratch.acc)
+	for i := range points {
+		points[i].X.PutBytesUnchecked(pubs[i][:32])
+		points[i].Y.PutBytesUnchecked(pubs[i][32:])
+	}
+}
+
+func (decred) Serialize(pub *[64]byte, compressed bool) []byte {
+	var x, y secp256k1.FieldVal
+	x.SetByteSlice(pub[:32])
+	y.SetByteSlice(pub[32:])
+	pk := secp256k1.NewPublicKey(&x, &y)
+	if compressed {
+		return pk.SerializeCompressed()
+	}
+	return pk.SerializeUncompressed()
+}
+
+// ToAffine converts points to affine coordinates with one field inversion
+// for the whole slice (Montgomery's trick), using acc, at least as long as
+// points, as scratch space. Points at infinity are left with a zero Z.
+func ToAffine(points []secp256k1.JacobianPoint, acc []secp256k1.FieldVal) {
+	if len(points) == 0 {
+		return
+	}
+
+	var one, inv, zinv, zinv2 secp256k1.FieldVal
+	one.SetInt(1)
+
+	z := func(p *secp256k1.JacobianPoint) *secp256k1.FieldVal {
+		p.Z.Normalize()
+		if p.Z.IsZero() {
+			return &one
+		}
+		return &p.Z
+	}
+
+	acc[0].Set(z(&points[0]))
+	for i := 1; i < len(points); i++ {
+		acc[i].Mul2(&acc[i-1], z(&points[i]))
+	}
+	inv.Set(&acc[len(points)-1]).Inverse()
+
+	for i := len(points) - 1; i >= 0; i-- {
+		p := &points[i]
+		if i > 0 {
+			zinv.Mul2(&inv, &acc[i-1])
+			inv.Mul(z(p))
+		} else {
+			zinv.Set(&inv)
+		}
+		if p.Z.IsZero() {
+			continue
+		}
+
+		zinv2.SquareVal(&zinv)
+		p.X.Mul(&zinv2).Normalize()
+		p.Y.Mul(zinv2.Mul(&zinv)).Normalize()
+		p.Z.SetInt(1)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build cgo
+
+package backend
+
+/*
+#include <stddef.h>
+
+// The library is compiled into the binary by go-ethereum's secp256k1
+// package, imported below; only its public API is declared here.
+typedef struct secp256k1_context_struct secp256k1_context;
+typedef struct {
+	unsigned char data[64];
+} secp256k1_pubkey;
+
+#define SECP256K1_CONTEXT_SIGN ((1 << 0) | (1 << 9))
+#define SECP256K1_EC_UNCOMPRESSED (1 << 1)
+
+extern secp256k1_context* secp256k1_context_create(unsigned int flags);
+extern int secp256k1_ec_pubkey_create(const secp256k1_context* ctx, secp256k1_pubkey* pubkey, const unsigned char* seckey);
+extern int secp256k1_ec_pubkey_serialize(const secp256k1_context* ctx, unsigned char* output, size_t* outputlen, const secp256k1_pubkey* pubkey, unsigned int flags);
+
+// pubkeys derives the uncompressed public keys of n scalars in one call,
+// writing X || Y without the 0x04 prefix.
+static void pubkeys(const secp256k1_context* ctx, const unsigned char* privs, unsigned char* pubs, size_t n) {
+	secp256k1_pubkey pk;
+	unsigned char out[65];
+	size_t len;
+	for (size_t i = 0; i < n; i++) {
+		len = sizeof(out);
+		if (!secp256k1_ec_pubkey_create(ctx, &pk, privs + 32*i)) {
+			continue;
+		}
+		secp256k1_ec_pubkey_serialize(ctx, out, &len, &pk, SECP256K1_EC_UNCOMPRESSED);
+		for (size_t j = 0; j < 64; j++) {
+			pubs[64*i + j] = out[1 + j];
+		}
+	}
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	_ "github.com/ethereum/go-ethereum/crypto/secp256k1"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+func init() {
+	register(libsecp256k1{ctx: C.secp256k1_context_create(C.SECP256K1_CONTEXT_SIGN)})
+}
+
+// libsecp256k1 is bitcoin-core's C library as vendored by go-ethereum,
+// called through secp256k1_ec_pubkey_create with its precomputed base
+// point tables. A batch is a single cgo call. The multiplication is
+// constant time, so it need not beat decred's variable-time one.
+type libsecp256k1 struct {
+	ctx *C.secp256k1_context
+}
+
+func (libsecp256k1) Name() string {
+	return "libsecp256k1"
+}
+
+func (b libsecp256k1) PublicKey(priv *[32]byte, pub *[64]byte) {
+	C.pubkeys(b.ctx, (*C.uchar)(unsafe.Pointer(&priv[0])), (*C.uchar)(unsafe.Pointer(&pub[0])), 1)
+}
+
+func (b libsecp256k1) PublicKeys(privs []key.Private, pubs [][64]byte) {
+	if len(privs) == 0 {
+		return
+	}
+	C.pubkeys(b.ctx, (*C.uchar)(unsafe.Pointer(&privs[0][0])), (*C.uchar)(unsafe.Pointer(&pubs[0][0])), C.size_t(len(privs)))
+}
+
+func (libsecp256k1) Serialize(pub *[64]byte, compressed bool) []byte {
+	return serialize(pub, compressed)
+}
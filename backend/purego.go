@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"math/big"
+
+	"github.com/seithhq/crypto-finder/key"
+)
+
+func init() {
+	register(purego{})
+}
+
+var (
+	fieldP, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	baseX, _  = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	baseY, _  = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+)
+
+// purego is a straightforward math/big double-and-add implementation with
+// no dependencies beyond the standard library. It is slow, but small enough
+// to audit, which makes it a reference for the other backends.
+type purego struct{}
+
+func (purego) Name() string {
+	return "purego"
+}
+
+// jacobian is a point (X/Z^2, Y/Z^3); Z == 0 is the point at infinity.
+type jacobian struct {
+	x, y, z *big.Int
+}
+
+func (purego) PublicKey(priv *[32]byte, pub *[64]byte) {
+	r := jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	for _, b := range priv {
+		for bit := 7; bit >= 0; bit-- {
+			r = double(r)
+			if b>>bit&1 == 1 {
+				r = addAffine(r, baseX, baseY)
+			}
+		}
+	}
+
+	zinv := new(big.Int).ModInverse(r.z, fieldP)
+	zinv2 := new(big.Int).Mul(zinv, zinv)
+	x := new(big.Int).Mul(r.x, zinv2)
+	y := new(big.Int).Mul(r.y, zinv2.Mul(zinv2, zinv))
+	x.Mod(x, fieldP).FillBytes(pub[:32])
+	y.Mod(y, fieldP).FillBytes(pub[32:])
+}
+
+func (b purego) PublicKeys(privs []key.Private, pubs [][64]byte) {
+	for i := range privs {
+		b.PublicKey((*[32]byte)(&privs[i]), &pubs[i])
+	}
+}
+
+func (purego) Serialize(pub *[64]byte, compressed bool) []byte {
+	return serialize(pub, compressed)
+}
+
+func mod(x *big.Int) *big.Int {
+	return x.Mod(x, fieldP)
+}
+
+// double uses the a = 0 doubling formulas.
+func double(p jacobian) jacobian {
+	if p.z.Sign() == 0 || p.y.Sign() == 0 {
+		return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	}
+
+	yy := mod(new(big.Int).Mul(p.y, p.y))
+	s := mod(new(big.Int).Lsh(new(big.Int).Mul(p.x, yy), 2))
+	m := mod(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(p.x, p.x)))
+
+	x := new(big.Int).Mul(m, m)
+	x = mod(x.Sub(x, new(big.Int).Lsh(s, 1)))
+	y := new(big.Int).Mul(m, new(big.Int).Sub(s, x))
+	y = mod(y.Sub(y, new(big.Int).Lsh(new(big.Int).Mul(yy, yy), 3)))
+	z := mod(new(big.Int).Lsh(new(big.Int).Mul(p.y, p.z), 1))
+
+	return jacobian{x, y, z}
+}
+
+// addAffine adds the affine point (qx, qy) to p.
+func addAffine(p jacobian, qx, qy *big.Int) jacobian {
+	if p.z.Sign() == 0 {
+		return jacobian{new(big.Int).Set(qx), new(big.Int).Set(qy), big.NewInt(1)}
+	}
+
+	zz := mod(new(big.Int).Mul(p.z, p.z))
+	u2 := mod(new(big.Int).Mul(qx, zz))
+	s2 := mod(new(big.Int).Mul(qy, new(big.Int).Mul(zz, p.z)))
+	h := mod(new(big.Int).Sub(u2, p.x))
+	r := mod(new(big.Int).Sub(s2, p.y))
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return double(p)
+		}
+		return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	}
+
+	hh := mod(new(big.Int).Mul(h, h))
+	hhh := mod(new(big.Int).Mul(hh, h))
+	v := mod(new(big.Int).Mul(p.x, hh))
+
+	x := new(big.Int).Mul(r, r)
+	x.Sub(x, hhh)
+	x = mod(x.Sub(x, new(big.Int).Lsh(v, 1)))
+	y := new(big.Int).Mul(r, new(big.Int).Sub(v, x))
+	y = mod(y.Sub(y, new(big.Int).Mul(p.y, hhh)))
+	z := mod(new(big.Int).Mul(h, p.z))
+
+	return jacobian{x, y, z}
+}
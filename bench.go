@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/seithhq/crypto-finder/address"
+	"github.com/seithhq/crypto-finder/backend"
+	"github.com/seithhq/crypto-finder/checkpoint"
+	"github.com/seithhq/crypto-finder/generator"
+	"github.com/seithhq/crypto-finder/key"
+	"github.com/seithhq/crypto-finder/source"
+	"github.com/seithhq/crypto-finder/walker"
+)
+
+const (
+	benchMask = "0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b??????"
+
+	// benchTarget stands in for the wallet being recovered when bench is run
+	// without -target, so that matching costs what it does in a real search.
+	benchTarget = "0x000000000000000000000000000000000000dead"
+
+	// sampleTime cuts an end-to-end measurement short of its sample size,
+	// so that candidates behind a slow KDF still measure in seconds.
+	sampleTime = 15 * time.Second
+
+	// measureTime is how long measure runs a stage, as go test -bench does.
+	measureTime = time.Second
+)
+
+// bench measures throughput on this machine: every backend, every
+// pipeline stage on its own and the whole pipeline end to end, after
+// checking that the walker and every backend derive the same addresses.
+// The end-to-end rate turns the keyspace size into an ETA.
+func bench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	var candidateOpts candidateFlags
+	var targetAddresses addressList
+	candidateOpts.register(fs)
+	verify := fs.Uint64("verify", 4096, "candidates to cross-check before timing")
+	backendName := fs.String("backend", "", "backend for the stage and end-to-end figures, default the fastest one measured")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of derivation workers")
+	sample := fs.Uint64("sample", 1<<16, "candidates to run through the whole pipeline")
+	keyspace := fs.Uint64("keyspace", 0, "keyspace size for the ETA, default the size of the candidates")
+	fs.Var(&targetAddresses, "target", targetUsage)
+	fs.Parse(args)
+
+	if candidateOpts.empty() {
+		candidateOpts.mask = benchMask
+	}
+	ks, size, _, err := benchKeyspace(&candidateOpts, *sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ks.Size() == 0 {
+		log.Fatal("no candidates to benchmark")
+	}
+	if *keyspace > 0 {
+		size = *keyspace
+	}
+	if len(targetAddresses) == 0 {
+		targetAddresses = addressList{benchTarget}
+	}
+	targets, err := candidateOpts.targets(targetAddresses)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var walk *walker.Walker
+	if m, ok := ks.(generator.Layered); ok {
+		walk = walker.New(m)
+		if err := verifyWalker(m, walk, min(*verify, m.Size())); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := backend.Verify(int(min(*verify, 1024))); err != nil {
+		log.Fatal(err)
+	}
+
+	unlock, err := candidateOpts.unlocker()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Wrong passwords unlock no key: time the backends on the default mask
+	// instead. Keystore and vault passwords are not even tried, as every
+	// try runs a KDF meant to take about a second.
+	defaultKeys := func() ([]key.Private, error) {
+		m, _ := generator.ParseMask(benchMask)
+		return sampleKeys(m, batchSize, nil)
+	}
+	var privs []key.Private
+	if candidateOpts.decrypts() {
+		privs, err = defaultKeys()
+	} else if privs, err = sampleKeys(ks, batchSize, unlock); err != nil && unlock != nil {
+		privs, err = defaultKeys()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("backends")
+	scalar := measure(func(n int) {
+		for i := 0; i < n; i++ {
+			addressFromKey(privs[i%len(privs)])
+		}
+	})
+	report("addressFromPrivate", scalar)
+
+	var fastest backend.Backend
+	var best int64
+	for _, be := range backend.All() {
+		d := address.NewDeriver(be)
+		var addr [address.Length]byte
+		single := measure(func(n int) {
+			for i := 0; i < n; i++ {
+				d.Derive((*[32]byte)(&privs[i%len(privs)]), &addr)
+			}
+		})
+
+		addrs := make([][address.Length]byte, len(privs))
+		batched := measure(func(n int) {
+			for left := n; left > 0; left -= len(privs) {
+				k := min(left, len(privs))
+				d.DeriveBatch(privs[:k], addrs[:k])
+			}
+		})
+
+		report(be.Name()+" Derive", single)
+		report(be.Name()+" DeriveBatch", batched)
+		if ns := batched.NsPerOp(); fastest == nil || ns < best {
+			fastest, best = be, ns
+		}
+	}
+	fmt.Printf("%-24s %s\n", "fastest backend", fastest.Name())
+
+	be := fastest
+	if *backendName != "" {
+		if be, err = backend.ByName(*backendName); err != nil {
+			log.Fatal(err)
+		}
+	}
+	match, err := newMatcher(targets, func() {})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("\nstages, backend %s\n", be.Name())
+	report("candidate generation", benchGenerate(ks, unlock != nil))
+	if unlock != nil {
+		report("unlock", benchUnlock(ks, unlock()))
+	}
+	if walk != nil {
+		incremental := benchWalk(walk, ks.Size())
+		report("incremental walk", incremental)
+		fmt.Printf("%-24s %.1fx\n", "walk speedup", float64(scalar.NsPerOp())/float64(max(incremental.NsPerOp(), 1)))
+	}
+	pubs := make([][64]byte, len(privs))
+	report("scalar multiplication", measure(func(n int) {
+		for left := n; left > 0; left -= len(privs) {
+			k := min(left, len(privs))
+			be.PublicKeys(privs[:k], pubs[:k])
+		}
+	}))
+
+	hasher := address.NewDeriver(nil)
+	be.PublicKeys(privs, pubs)
+	var addr [address.Length]byte
+	report("keccak256", measure(func(n int) {
+		for i := 0; i < n; i++ {
+			hasher.FromPublic(&pubs[i%len(pubs)], &addr)
+		}
+	}))
+
+	addrs := make([][address.Length]byte, len(privs))
+	address.NewDeriver(be).DeriveBatch(privs, addrs)
+	var r result
+	report("matching", measure(func(n int) {
+		for i := 0; i < n; i++ {
+			r.hits = r.hits[:0]
+			match.check(&r, checkpoint.Hit{}, &addrs[i%len(addrs)])
+		}
+	}))
+
+	h := checkpoint.Hit{Private: privs[0].Hex(), Address: addressFromKey(privs[0])}
+	report("output", measure(func(n int) {
+		for i := 0; i < n; i++ {
+			printHit(io.Discard, h)
+		}
+	}))
+
+	n, elapsed := endToEnd(ks, walk, unlock, be, max(*workers, 1), match, min(*sample, ks.Size()))
+	rate := float64(n) / elapsed.Seconds()
+	fmt.Printf("\n%-24s %12.0f keys/s %8d candidates %d workers\n", "end-to-end", rate, n, max(*workers, 1))
+
+	if size > 0 {
+		fmt.Printf("%-24s %12d candidates, ETA %s\n", "keyspace", size, eta(size, rate))
+	}
+}
+
+// benchKeyspace opens the candidates given to bench or plan together with
+// the size of the whole search and its spec. Streamed input is sampled on
+// its first lines and counted to the end for the size.
+func benchKeyspace(opts *candidateFlags, sample uint64) (generator.Keyspace, uint64, string, error) {
+	ks, input, spec, err := opts.open()
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if ks != nil {
+		return ks, ks.Size(), spec, nil
+	}
+	defer input.Close()
+
+	var lines stringKeyspace
+	var n uint64
+	err = source.Lines(input, func(i uint64, line string) bool {
+		if i < sample {
+			lines = append(lines, line)
+		}
+		n = i + 1
+		return true
+	})
+	return lines, n, spec, err
+}
+
+// stringKeyspace is an in-memory candidate list.
+type stringKeyspace []string
+
+func (s stringKeyspace) Size() uint64 { return uint64(len(s)) }
+
+func (s stringKeyspace) At(i uint64) (string, bool) { return s[i], true }
+
+func (s stringKeyspace) Each(from, to uint64, fn func(i uint64, key string) bool) {
+	for i := from; i < to && i < uint64(len(s)); i++ {
+		if !fn(i, s[i]) {
+			return
+		}
+	}
+}
+
+// sampleKeys returns up to n valid keys from the start of ks, unlocking
+// them first unless newUnlocker is nil. At most n candidates are unlocked.
+func sampleKeys(ks generator.Keyspace, n int, newUnlocker func() unlocker) ([]key.Private, error) {
+	var privs []key.Private
+	var unlock unlocker
+	if newUnlocker != nil {
+		unlock = newUnlocker()
+	}
+	ks.Each(0, min(ks.Size(), uint64(n)), func(_ uint64, candidate string) bool {
+		if unlock == nil {
+			if prv, err := key.Parse(candidate); err == nil {
+				privs = append(privs, prv)
+			}
+			return len(privs) < n
+		}
+		keys, _ := unlock(candidate)
+		for _, k := range keys {
+			privs = append(privs, k.key)
+		}
+		return len(privs) < n
+	})
+	if len(privs) == 0 {
+		return nil, fmt.Errorf("no valid key among the candidates")
+	}
+	return privs, nil
+}
+
+// benchGenerate measures producing and, unless they are texts, decoding
+// candidates, what the feed does for every key that is not walked.
+func benchGenerate(ks generator.Keyspace, texts bool) timing {
+	return measure(func(count int) {
+		for left := uint64(count); left > 0; {
+			n := min(left, ks.Size())
+			ks.Each(0, n, func(_ uint64, candidate string) bool {
+				if !texts {
+					key.Parse(candidate)
+				}
+				return true
+			})
+			left -= n
+		}
+	})
+}
+
+// benchUnlock measures turning text candidates into keys, e.g. mnemonics
+// through PBKDF2 and BIP32.
+func benchUnlock(ks generator.Keyspace, unlock unlocker) timing {
+	var texts []string
+	ks.Each(0, ks.Size(), func(_ uint64, text string) bool {
+		texts = append(texts, text)
+		return len(texts) < batchSize
+	})
+	return measure(func(n int) {
+		for i := 0; i < n; i++ {
+			unlock(texts[i%len(texts)])
+		}
+	})
+}
+
+func benchWalk(walk *walker.Walker, size uint64) timing {
+	return measure(func(count int) {
+		for left := uint64(count); left > 0; {
+			n := min(left, size)
+			walk.Each(0, n, func(uint64, *key.Private, *[20]byte, error) bool { return true })
+			left -= n
+		}
+	})
+}
+
+// endToEnd runs the first n candidates of ks through the same feed, pool
+// and workers as a search, discarding the output, and returns how many it
+// tested and how long that took; it stops feeding after sampleTime. walk
+// is nil unless ks is a mask, unlock unless ks holds texts.
+func endToEnd(ks generator.Keyspace, walk *walker.Walker, unlock func() unlocker, be backend.Backend, workers int, match *matcher, n uint64) (uint64, time.Duration) {
+	done := make(chan struct{})
+	timer := time.AfterFunc(sampleTime, func() { close(done) })
+	defer timer.Stop()
+	pending := []checkpoint.Range{{From: 0, To: n}}
+
+	feed := feedKeyspace(ks, unlock != nil, pending, done)
+	if walk != nil {
+		feed = feedWalk(pending, done)
+	}
+
+	begin := time.Now()
+	tested := uint64(0)
+	for r := range runPool(workers, feed, newWorker(be, walk, unlock, match)) {
+		tested += r.to - r.index
+		for _, h := range r.hits {
+			printHit(io.Discard, h)
+		}
+	}
+	return tested, time.Since(begin)
+}
+
+// eta formats how long size candidates take at rate keys per second.
+func eta(size uint64, rate float64) string {
+	seconds := float64(size) / rate
+	if seconds > float64(365*24*time.Hour/time.Second) {
+		return fmt.Sprintf("%.3g years", seconds/(365*24*3600))
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+func verifyWalker(m generator.Keyspace, walk *walker.Walker, n uint64) error {
+	var err error
+	walk.Each(0, n, func(i uint64, priv *key.Private, addr *[20]byte, keyErr error) bool {
+		want, ok := m.At(i)
+		if !ok || priv.Hex() != want {
+			err = fmt.Errorf("candidate %d: walker key %s, mask key %s", i, priv.Hex(), want)
+			return false
+		}
+		address, parseErr := addressFromPrivate(want)
+		if (keyErr == nil) != (parseErr == nil) {
+			err = fmt.Errorf("candidate %d: walker error %v, addressFromPrivate error %v", i, keyErr, parseErr)
+			return false
+		}
+		if keyErr == nil && hex.EncodeToString(addr[:]) != address {
+			err = fmt.Errorf("candidate %d: walker address %x, addressFromPrivate %s", i, addr[:], address)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// timing is what measure found: n operations took elapsed and made allocs
+// heap allocations.
+type timing struct {
+	n       int
+	elapsed time.Duration
+	allocs  uint64
+}
+
+func (t timing) NsPerOp() int64 {
+	return t.elapsed.Nanoseconds() / int64(max(t.n, 1))
+}
+
+func (t timing) AllocsPerOp() int64 {
+	return int64(t.allocs) / int64(max(t.n, 1))
+}
+
+// measure calls fn(n) with a growing n until one call takes measureTime,
+// the way testing.Benchmark does, which the binary does not link. The
+// benchmarks proper live with their packages, e.g. address and walker.
+func measure(fn func(n int)) timing {
+	var before, after runtime.MemStats
+	for n := 1; ; {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		fn(n)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		if elapsed >= measureTime || n >= 1e9 {
+			return timing{n: n, elapsed: elapsed, allocs: after.Mallocs - before.Mallocs}
+		}
+		// Aim past measureTime, growing at most 100 times at once.
+		next := int(1.2 * float64(n) * float64(measureTime) / float64(max(elapsed, 1)))
+		n = min(max(next, n+1), 100*n, 1e9)
+	}
+}
+
+func report(name string, r timing) {
+	keys := 0.0
+	if ns := r.NsPerOp(); ns > 0 {
+		keys = 1e9 / float64(ns)
+	}
+	fmt.Printf("%-24s %12.0f keys/s %8d ns/key %6d allocs/key\n", name, keys, r.NsPerOp(), r.AllocsPerOp())
+}
@@ -0,0 +1,215 @@
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/seithhq/crypto-finder/encrypted"
+)
+
+var ErrMismatch = errors.New("checkpoint belongs to a different search")
+
+// Range is the half-open index range [From, To).
+type Range struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// Hit is a reported candidate. Secret is the mnemonic or password that
+// unlocked Private, and Path its derivation path, for searches that do
+// not test keys directly.
+type Hit struct {
+	Private string `json:"private"`
+	Address string `json:"address"`
+	Path    string `json:"path,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// State is the progress of one search: which candidate indexes have been
+// tested and what was found among them. Spec identifies the keyspace so a
+// state file is never resumed against different parameters.
+type State struct {
+	Spec  string  `json:"spec"`
+	Start uint64  `json:"start"`
+	End   uint64  `json:"end"`
+	Done  []Range `json:"done"`
+	Hits  []Hit   `json:"hits"`
+
+	// Encryption is the KDF Save encrypts the state with, nil to write it
+	// in the clear. Load sets it to that of an encrypted file.
+	Encryption *encrypted.KDF `json:"-"`
+
+	mu sync.Mutex
+}
+
+func New(spec string, start, end uint64) *State {
+	return &State{Spec: spec, Start: start, End: end}
+}
+
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{}
+	if encrypted.Is(data) {
+		passphrase, err := encrypted.Passphrase()
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+		}
+		r, err := encrypted.NewReader(bytes.NewReader(data), passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+		}
+		kdf := r.KDF()
+		s.Encryption = &kdf
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Check returns ErrMismatch unless s was saved by a search with the same
+// spec and index range.
+func (s *State) Check(spec string, start, end uint64) error {
+	if s.Spec != spec || s.Start != start || s.End != end {
+		return fmt.Errorf("%w: saved %q [%d, %d), running %q [%d, %d)",
+			ErrMismatch, s.Spec, s.Start, s.End, spec, start, end)
+	}
+	return nil
+}
+
+// Marshal encodes the state the way Save writes it.
+func (s *State) Marshal() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// Save writes the state next to path and renames it into place, so a crash
+// mid-write leaves the previous checkpoint intact.
+func (s *State) Save(path string) error {
+	data, err := s.Marshal()
+	if err != nil {
+		return err
+	}
+	if s.Encryption != nil {
+		if data, err = seal(data, *s.Encryption); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func seal(data []byte, kdf encrypted.KDF) ([]byte, error) {
+	passphrase, err := encrypted.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w, err := encrypted.NewWriter(&buf, passphrase, kdf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Complete marks [from, to) as tested, merging it into the neighbouring
+// ranges. Workers finish out of order, but only by a few batches, so Done
+// stays short.
+func (s *State) Complete(from, to uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := sort.Search(len(s.Done), func(j int) bool { return s.Done[j].To >= from })
+	j := i
+	for j < len(s.Done) && s.Done[j].From <= to {
+		from = min(from, s.Done[j].From)
+		to = max(to, s.Done[j].To)
+		j++
+	}
+
+	s.Done = append(s.Done[:i], append([]Range{{From: from, To: to}}, s.Done[j:]...)...)
+}
+
+func (s *State) AddHit(h Hit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Hits = append(s.Hits, h)
+}
+
+// Pending returns the ranges of [Start, End) not yet tested, in order.
+func (s *State) Pending() []Range {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make([]Range, 0, len(s.Done)+1)
+	next := s.Start
+	for _, r := range s.Done {
+		if to := min(r.From, s.End); to > next {
+			pending = append(pending, Range{From: next, To: to})
+		}
+		next = max(next, r.To)
+		if next >= s.End {
+			return pending
+		}
+	}
+	if next < s.End {
+		pending = append(pending, Range{From: next, To: s.End})
+	}
+
+	return pending
+}
+
+// Tested returns how many indexes have been marked complete.
+func (s *State) Tested() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := uint64(0)
+	for _, r := range s.Done {
+		n += r.To - r.From
+	}
+	return n
+}
@@ -0,0 +1,168 @@
+package checkpoint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/seithhq/crypto-finder/encrypted"
+)
+
+func TestComplete(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		done  []Range
+		want  []Range
+		count uint64
+	}{
+		{"in order", []Range{{0, 10}, {10, 20}, {20, 30}}, []Range{{0, 30}}, 30},
+		{"out of order", []Range{{20, 30}, {0, 10}, {10, 20}}, []Range{{0, 30}}, 30},
+		{"gaps", []Range{{40, 50}, {0, 10}, {20, 30}}, []Range{{0, 10}, {20, 30}, {40, 50}}, 30},
+		{"gap filled", []Range{{0, 10}, {20, 30}, {10, 20}}, []Range{{0, 30}}, 30},
+		{"overlapping", []Range{{0, 15}, {10, 25}, {5, 8}}, []Range{{0, 25}}, 25},
+		{"spanning several", []Range{{10, 20}, {30, 40}, {50, 60}, {15, 55}}, []Range{{10, 60}}, 50},
+		{"before all", []Range{{50, 60}, {30, 40}, {0, 10}}, []Range{{0, 10}, {30, 40}, {50, 60}}, 30},
+	} {
+		s := New("spec", 0, 100)
+		for _, r := range tc.done {
+			s.Complete(r.From, r.To)
+		}
+		if !reflect.DeepEqual(s.Done, tc.want) {
+			t.Errorf("%s: Done = %v, want %v", tc.name, s.Done, tc.want)
+		}
+		if s.Tested() != tc.count {
+			t.Errorf("%s: Tested = %d, want %d", tc.name, s.Tested(), tc.count)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		start, end uint64
+		done       []Range
+		want       []Range
+	}{
+		{"fresh", 0, 100, nil, []Range{{0, 100}}},
+		{"finished", 0, 100, []Range{{0, 100}}, []Range{}},
+		{"holes", 0, 100, []Range{{10, 20}, {50, 60}}, []Range{{0, 10}, {20, 50}, {60, 100}}},
+		{"from start", 30, 100, []Range{{30, 40}}, []Range{{40, 100}}},
+		{"clipped at start", 30, 100, []Range{{10, 20}, {25, 35}}, []Range{{35, 100}}},
+		{"clipped at end", 0, 50, []Range{{10, 20}, {60, 70}}, []Range{{0, 10}, {20, 50}}},
+		{"done past end", 0, 50, []Range{{40, 70}}, []Range{{0, 40}}},
+		{"empty", 50, 50, []Range{{60, 70}}, []Range{}},
+	} {
+		s := New("spec", tc.start, tc.end)
+		for _, r := range tc.done {
+			s.Complete(r.From, r.To)
+		}
+		if got := s.Pending(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Pending = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// Every index is either pending or done once a search stops, whatever order
+// its batches completed in.
+func TestPendingCoversUndone(t *testing.T) {
+	s := New("spec", 3, 97)
+	for _, r := range []Range{{40, 45}, {3, 7}, {50, 60}, {45, 50}, {90, 97}, {7, 9}} {
+		s.Complete(r.From, r.To)
+	}
+	covered := make([]int, 100)
+	for _, rs := range [][]Range{s.Done, s.Pending()} {
+		for _, r := range rs {
+			for i := r.From; i < r.To; i++ {
+				covered[i]++
+			}
+		}
+	}
+	for i := 3; i < 97; i++ {
+		if covered[i] != 1 {
+			t.Fatalf("index %d is pending or done %d times", i, covered[i])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := New("mask=0a5c*", 0, 100)
+	if err := s.Check("mask=0a5c*", 0, 100); err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []struct {
+		spec       string
+		start, end uint64
+	}{
+		{"mask=0a5d*", 0, 100},
+		{"mask=0a5c*", 1, 100},
+		{"mask=0a5c*", 0, 99},
+	} {
+		if err := s.Check(other.spec, other.start, other.end); !errors.Is(err, ErrMismatch) {
+			t.Errorf("Check(%q, %d, %d) = %v, want %v", other.spec, other.start, other.end, err, ErrMismatch)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv(encrypted.PassphraseEnv, "s3cret")
+	for _, kdf := range []*encrypted.KDF{nil, {Name: "scrypt", A: 10, B: 8, C: 1}} {
+		path := filepath.Join(t.TempDir(), "state.json")
+		s := New("mnemonic=abandon ?", 0, 2048)
+		s.Encryption = kdf
+		s.Complete(0, 64)
+		s.Complete(128, 192)
+		s.AddHit(Hit{Private: "0a5c", Address: "5bec", Path: "m/44'/60'/0'/0/0", Secret: "abandon about"})
+		if err := s.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		// Saving again replaces the file.
+		if err := s.Save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted.Is(data) != (kdf != nil) || (kdf != nil) == strings.Contains(string(data), "abandon") {
+			t.Fatalf("encryption %v: file %q", kdf, data[:min(len(data), 40)])
+		}
+
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded.Encryption, kdf) {
+			t.Errorf("Load sets encryption %v, want %v", loaded.Encryption, kdf)
+		}
+		if err := loaded.Check(s.Spec, s.Start, s.End); err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(loaded.Done, s.Done) || !reflect.DeepEqual(loaded.Hits, s.Hits) {
+			t.Errorf("loaded %v %v, saved %v %v", loaded.Done, loaded.Hits, s.Done, s.Hits)
+		}
+		if !reflect.DeepEqual(loaded.Pending(), []Range{{64, 128}, {192, 2048}}) {
+			t.Errorf("loaded state pending %v", loaded.Pending())
+		}
+
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("Save leaves %d files behind", len(entries))
+		}
+	}
+
+	t.Setenv(encrypted.PassphraseEnv, "wrong")
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := New("spec", 0, 1)
+	s.Encryption = &encrypted.KDF{Name: "scrypt", A: 10, B: 8, C: 1}
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(encrypted.PassphraseEnv, "s3cret")
+	if _, err := Load(path); !errors.Is(err, encrypted.ErrPassphrase) {
+		t.Fatalf("Load with the wrong passphrase = %v, want %v", err, encrypted.ErrPassphrase)
+	}
+}
@@ -0,0 +1,401 @@
+package encrypted
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv names the environment variable holding the passphrase of
+// encrypted files.
+const PassphraseEnv = "CRYPTO_FINDER_PASSPHRASE"
+
+// Magic starts every encrypted file.
+const Magic = "CFENC\x00v1"
+
+const (
+	saltSize   = 16
+	headerSize = len(Magic) + 1 + 3*4 + saltSize
+	// chunkSize is how much plaintext one sealed chunk carries.
+	chunkSize = 64 << 10
+	lastChunk = 1 << 31
+)
+
+var (
+	ErrNoPassphrase = fmt.Errorf("no passphrase: set %s", PassphraseEnv)
+	ErrPassphrase   = errors.New("wrong passphrase or corrupted file")
+	ErrTruncated    = errors.New("encrypted file is truncated")
+	ErrFormat       = errors.New("not an encrypted file")
+)
+
+// KDF is the key derivation turning the passphrase into the AES-256 key
+// of a file.
+type KDF struct {
+	Name string
+	// For argon2id, A is the time, B the memory in KiB and C the threads;
+	// for scrypt, A is log2 N, B is r and C is p.
+	A, B, C uint32
+}
+
+var (
+	Argon2id = KDF{Name: "argon2id", A: 3, B: 64 << 10, C: 4}
+	Scrypt   = KDF{Name: "scrypt", A: 17, B: 8, C: 1}
+)
+
+var kdfIDs = map[string]byte{"argon2id": 1, "scrypt": 2}
+
+// ParseKDF returns the default parameters of the KDF called name.
+func ParseKDF(name string) (KDF, error) {
+	switch name {
+	case "argon2id":
+		return Argon2id, nil
+	case "scrypt":
+		return Scrypt, nil
+	}
+	return KDF{}, fmt.Errorf("kdf %q: want argon2id or scrypt", name)
+}
+
+// Bounds on the KDF parameters of a file, which come from its header and
+// so must not make reading it exhaust memory or run for hours.
+const (
+	maxMemory = 4 << 30
+	maxPasses = 64
+)
+
+func (k KDF) derive(passphrase, salt []byte) ([]byte, error) {
+	switch k.Name {
+	case "argon2id":
+		if k.A < 1 || k.A > maxPasses || k.B < 8 || uint64(k.B) > maxMemory>>10 || k.C < 1 || k.C > 255 {
+			return nil, fmt.Errorf("%w: argon2id parameters t=%d m=%d p=%d", ErrFormat, k.A, k.B, k.C)
+		}
+		return argon2.IDKey(passphrase, salt, k.A, k.B, uint8(k.C), 32), nil
+	case "scrypt":
+		// scrypt uses 128*r*N bytes, p times over.
+		if k.A < 1 || k.A > 30 || k.B < 1 || k.C < 1 || k.C > maxPasses || uint64(k.B) > maxMemory>>(7+k.A) {
+			return nil, fmt.Errorf("%w: scrypt parameters N=2^%d r=%d p=%d", ErrFormat, k.A, k.B, k.C)
+		}
+		return scrypt.Key(passphrase, salt, 1<<k.A, int(k.B), int(k.C), 32)
+	}
+	return nil, fmt.Errorf("%w: kdf %q", ErrFormat, k.Name)
+}
+
+// Passphrase returns the passphrase of encrypted files from the
+// environment.
+func Passphrase() ([]byte, error) {
+	p, ok := os.LookupEnv(PassphraseEnv)
+	if !ok || p == "" {
+		return nil, ErrNoPassphrase
+	}
+	return []byte(p), nil
+}
+
+// Is reports whether data starts like an encrypted file.
+func Is(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Encrypt seals plaintext with AES-GCM under a random nonce, which it
+// puts in front of the ciphertext. aad is authenticated along.
+func Encrypt(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt opens what Encrypt sealed, taking the nonce from the front.
+func Decrypt(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrTruncated
+	}
+	n := aead.NonceSize()
+	plain, err := aead.Open(nil, sealed[:n], sealed[n:], aad)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+// keys caches derived keys: a deliberately slow KDF should run once per
+// file, not on every checkpoint save. Writers reuse the salt and key of
+// their passphrase and KDF, which is safe with random nonces.
+var keys struct {
+	sync.Mutex
+	bySalt  map[string][]byte
+	writers map[string][]byte
+}
+
+func cacheID(passphrase []byte, kdf KDF, salt []byte) string {
+	h := sha256.New()
+	h.Write(passphrase)
+	fmt.Fprintf(h, "\x00%s/%d/%d/%d\x00", kdf.Name, kdf.A, kdf.B, kdf.C)
+	h.Write(salt)
+	return string(h.Sum(nil))
+}
+
+func key(passphrase []byte, kdf KDF, salt []byte) ([]byte, error) {
+	id := cacheID(passphrase, kdf, salt)
+	keys.Lock()
+	defer keys.Unlock()
+	if k, ok := keys.bySalt[id]; ok {
+		return k, nil
+	}
+	k, err := kdf.derive(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if keys.bySalt == nil {
+		keys.bySalt = make(map[string][]byte)
+	}
+	keys.bySalt[id] = k
+	return k, nil
+}
+
+func writerSalt(passphrase []byte, kdf KDF) ([]byte, error) {
+	id := cacheID(passphrase, kdf, nil)
+	keys.Lock()
+	defer keys.Unlock()
+	if salt, ok := keys.writers[id]; ok {
+		return salt, nil
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if keys.writers == nil {
+		keys.writers = make(map[string][]byte)
+	}
+	keys.writers[id] = salt
+	return salt, nil
+}
+
+func newAEAD(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkAAD binds a chunk to the header of its file, its position and
+// whether it is the last one, so chunks can be neither swapped, dropped
+// nor cut off at the end.
+func chunkAAD(header []byte, index uint64, length uint32) []byte {
+	aad := make([]byte, 0, len(header)+12)
+	aad = append(aad, header...)
+	aad = binary.BigEndian.AppendUint64(aad, index)
+	return binary.BigEndian.AppendUint32(aad, length)
+}
+
+// Writer encrypts a stream into the file format: the header, holding the
+// KDF and its salt, then chunks of at most chunkSize bytes of plaintext.
+// Every chunk is its plaintext length, with the top bit set on the last
+// chunk, and the plaintext sealed by Encrypt.
+type Writer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	index  uint64
+	closed bool
+}
+
+// NewWriter starts an encrypted file on w. Close must be called to
+// finish it; it does not close w.
+func NewWriter(w io.Writer, passphrase []byte, kdf KDF) (*Writer, error) {
+	id, ok := kdfIDs[kdf.Name]
+	if !ok {
+		return nil, fmt.Errorf("kdf %q: want argon2id or scrypt", kdf.Name)
+	}
+	salt, err := writerSalt(passphrase, kdf)
+	if err != nil {
+		return nil, err
+	}
+	k, err := key(passphrase, kdf, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(k)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(Magic), id)
+	header = binary.BigEndian.AppendUint32(header, kdf.A)
+	header = binary.BigEndian.AppendUint32(header, kdf.B)
+	header = binary.BigEndian.AppendUint32(header, kdf.C)
+	header = append(header, salt...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encrypted writer")
+	}
+	n := 0
+	for len(p) > 0 {
+		// Keep a full chunk buffered: only Close knows which is last.
+		if len(w.buf) == chunkSize {
+			if err := w.seal(false); err != nil {
+				return n, err
+			}
+		}
+		k := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+k]
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// Flush seals what was written since the last chunk into a chunk of its
+// own, so that it survives a crash before Close. A reader of such a file
+// gets everything flushed, then ErrTruncated.
+func (w *Writer) Flush() error {
+	if w.closed || len(w.buf) == 0 {
+		return nil
+	}
+	return w.seal(false)
+}
+
+// Close seals the last chunk, empty if need be.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *Writer) seal(last bool) error {
+	length := uint32(len(w.buf))
+	if last {
+		length |= lastChunk
+	}
+	sealed, err := Encrypt(w.aead, w.buf, chunkAAD(w.header, w.index, length))
+	if err != nil {
+		return err
+	}
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], length)
+	if _, err := w.w.Write(prefix[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(sealed); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	w.index++
+	return nil
+}
+
+// Reader decrypts a stream written by Writer, failing with ErrTruncated
+// if it ends before its last chunk.
+type Reader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	kdf    KDF
+	header []byte
+	plain  []byte
+	index  uint64
+	done   bool
+	// err sticks: after a bad chunk, the stream is out of step.
+	err error
+}
+
+// NewReader reads the header of an encrypted file from r and derives its
+// key.
+func NewReader(r io.Reader, passphrase []byte) (*Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil || !Is(header) {
+		return nil, ErrFormat
+	}
+
+	var kdf KDF
+	for name, id := range kdfIDs {
+		if header[len(Magic)] == id {
+			kdf.Name = name
+		}
+	}
+	params := header[len(Magic)+1:]
+	kdf.A = binary.BigEndian.Uint32(params[0:])
+	kdf.B = binary.BigEndian.Uint32(params[4:])
+	kdf.C = binary.BigEndian.Uint32(params[8:])
+	salt := params[12:]
+
+	k, err := key(passphrase, kdf, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(k)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: r, aead: aead, kdf: kdf, header: header}, nil
+}
+
+// KDF returns the key derivation of the file, to write it back the same way.
+func (r *Reader) KDF() KDF {
+	return r.kdf
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *Reader) next() error {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r.r, prefix[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+	length := binary.BigEndian.Uint32(prefix[:])
+	size := length &^ lastChunk
+	if size > chunkSize {
+		return ErrPassphrase
+	}
+
+	sealed := make([]byte, r.aead.NonceSize()+int(size)+r.aead.Overhead())
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+	plain, err := Decrypt(r.aead, sealed, chunkAAD(r.header, r.index, length))
+	if err != nil {
+		return err
+	}
+	r.plain = plain
+	r.index++
+	r.done = length&lastChunk != 0
+	return nil
+}
@@ -0,0 +1,116 @@
+package encrypted
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fast keeps the KDF cheap; the format does not depend on its cost.
+var fast = KDF{Name: "scrypt", A: 10, B: 8, C: 1}
+
+func seal(t *testing.T, plain []byte, kdf KDF, flushEvery int) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []byte("secret"), kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(plain) > 0 {
+		n := min(len(plain), flushEvery)
+		if _, err := w.Write(plain[:n]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		plain = plain[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func open(sealed []byte, passphrase string) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(sealed), []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 7} {
+		plain := bytes.Repeat([]byte("0a5c2dff\n"), size/9+1)[:size]
+		for _, kdf := range []KDF{fast, {Name: "argon2id", A: 1, B: 64, C: 1}} {
+			got, err := open(seal(t, plain, kdf, chunkSize+chunkSize/2), "secret")
+			if err != nil {
+				t.Fatalf("%s, %d bytes: %v", kdf.Name, size, err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("%s, %d bytes: round trip changed the plaintext", kdf.Name, size)
+			}
+		}
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	if _, err := open(seal(t, []byte("hits"), fast, 4), "guess"); !errors.Is(err, ErrPassphrase) {
+		t.Fatalf("got %v, want ErrPassphrase", err)
+	}
+}
+
+func TestTamper(t *testing.T) {
+	sealed := seal(t, bytes.Repeat([]byte("x"), 2*chunkSize), fast, chunkSize)
+	for _, at := range []int{headerSize - 1, headerSize + 40, len(sealed) - 1} {
+		bad := bytes.Clone(sealed)
+		bad[at] ^= 1
+		if _, err := open(bad, "secret"); err == nil {
+			t.Errorf("flipping byte %d went unnoticed", at)
+		}
+	}
+}
+
+func TestTruncatedKeepsFlushed(t *testing.T) {
+	sealed := seal(t, []byte("hit 1\nhit 2\n"), fast, 6)
+	// Cut off the empty last chunk, as a crash before Close does.
+	cut := sealed[:len(sealed)-4-12-16]
+	r, err := NewReader(bytes.NewReader(cut), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("got %v, want ErrTruncated", err)
+	}
+	if string(got) != "hit 1\nhit 2\n" {
+		t.Fatalf("read %q before the truncation, want both hits", got)
+	}
+}
+
+func TestHostileHeader(t *testing.T) {
+	sealed := seal(t, []byte("state"), Argon2id, 5)
+	for _, tc := range []struct {
+		id      byte
+		a, b, c uint32
+	}{
+		{1, 1, 1 << 31, 1},  // argon2id asking for 2 TiB
+		{1, 1 << 20, 64, 1}, // argon2id running a million passes
+		{2, 30, 8, 1},       // scrypt asking for 1 TiB
+		{2, 10, 1 << 30, 1}, // scrypt with a huge r
+		{2, 10, 8, 1 << 20}, // scrypt with a huge p
+		{9, 1, 1, 1},        // unknown KDF
+	} {
+		bad := bytes.Clone(sealed)
+		bad[len(Magic)] = tc.id
+		params := bad[len(Magic)+1:]
+		binary.BigEndian.PutUint32(params[0:], tc.a)
+		binary.BigEndian.PutUint32(params[4:], tc.b)
+		binary.BigEndian.PutUint32(params[8:], tc.c)
+		if _, err := open(bad, "secret"); !errors.Is(err, ErrFormat) {
+			t.Errorf("kdf %d (%d, %d, %d): got %v, want ErrFormat", tc.id, tc.a, tc.b, tc.c, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package generator
+
+import "errors"
+
+const (
+	HexChars  = "0123456789abcdef"
+	KeyLength = 64
+)
+
+var ErrTooLarge = errors.New("keyspace does not fit in 64 bits")
+
+// Keyspace is an indexable source of hex private-key candidates: every
+// index in [0, Size()) maps to the same candidate on every run, so a search
+// can be split, resumed or audited by index range.
+type Keyspace interface {
+	Size() uint64
+	// At returns the candidate at index i, or false when Each skips it,
+	// e.g. as a duplicate. Size counts the skipped indexes too.
+	At(i uint64) (string, bool)
+	// Each calls fn with the candidates at indexes [from, to) in order until
+	// the range is exhausted or fn returns false.
+	Each(from, to uint64, fn func(i uint64, key string) bool)
+}
+
+// Deduplicated is implemented by keyspaces that yield repeated candidates
+// only once. Duplicates returns how many repeats were dropped, or false
+// when that is too expensive to count.
+type Deduplicated interface {
+	Duplicates() (uint64, bool)
+}
+
+// Layered is implemented by keyspaces made of layouts, which a walker can
+// enumerate without a scalar multiplication per candidate.
+type Layered interface {
+	Keyspace
+	// EachLayout calls fn with the layouts holding the indexes [from, to)
+	// in order until fn returns false.
+	EachLayout(from, to uint64, fn func(Layout) bool)
+}
@@ -0,0 +1,560 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxLayouts = 4096
+
+var ErrMaskSyntax = errors.New("mask syntax error")
+
+// Mask is a compiled candidate pattern. The syntax is one atom per key
+// position, optionally followed by a quantifier:
+//
+//	0-9a-f    literal hex character (case-insensitive)
+//	?         any hex character
+//	[0-7b6]   character class with ranges
+//	*         gap of any length, same as ?{0,64}; "..." is accepted too
+//	{n}       repeat the previous atom exactly n times
+//	{m,n}     repeat the previous atom between m and n times
+//
+// A mask compiles into one layout per way of choosing the repeat counts so
+// that the key is exactly KeyLength characters long, e.g. "0a5c??ffb9*".
+//
+// Layouts may overlap, e.g. the prefix and suffix splits of a fragment
+// whose edge characters repeat. A key mask yields every key only from the
+// first layout holding it: indexes of the later copies are skipped, so
+// Size still counts them and indexes stay stable.
+type Mask struct {
+	Source  string
+	Length  int
+	layouts []layout
+	// offsets[i] is the index of the first candidate of layouts[i].
+	offsets  []uint64
+	size     uint64
+	overflow bool
+	// shadows[i] lists the earlier layouts overlapping layouts[i].
+	shadows    [][]shadow
+	duplicates uint64
+	counted    bool
+}
+
+type atom struct {
+	set      string
+	min, max int
+}
+
+// layout holds the character set of every position.
+type layout []string
+
+// shadow is an earlier layout overlapping a later one. A candidate of the
+// later layout was already yielded by the earlier one when its character
+// at every position pos[j] is in sets[j]; at the other positions the
+// earlier layout accepts every character of the later one anyway.
+type shadow struct {
+	pos  []int
+	sets []uint16
+}
+
+// maxTerms bounds the inclusion-exclusion terms spent on counting
+// duplicates. Masks needing more, like several overlapping gaps, are
+// still deduplicated but their duplicates are not counted.
+const maxTerms = 1 << 20
+
+func ParseMask(src string) (*Mask, error) {
+	m, err := CompileMask(src, KeyLength)
+	if err != nil {
+		return nil, err
+	}
+	if m.overflow {
+		return nil, fmt.Errorf("mask %q: %w", src, ErrTooLarge)
+	}
+	m.dedupe()
+
+	return m, nil
+}
+
+// CompileMask compiles a mask for hex strings of the given length, so the
+// same syntax can describe addresses as well as keys.
+func CompileMask(src string, length int) (*Mask, error) {
+	atoms, err := parseAtoms(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(src), "0x")))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mask{Source: src, Length: length}
+	counts := make([]int, len(atoms))
+	if err := m.expand(atoms, counts, 0, length); err != nil {
+		return nil, err
+	}
+	if len(m.layouts) == 0 {
+		return nil, fmt.Errorf("%w: %q cannot be exactly %d characters long", ErrMaskSyntax, src, length)
+	}
+
+	m.index()
+
+	return m, nil
+}
+
+// index computes the offset of every layout and the keyspace size.
+func (m *Mask) index() {
+	m.offsets, m.size, m.overflow = m.offsets[:0], 0, false
+	for _, l := range m.layouts {
+		m.offsets = append(m.offsets, m.size)
+		n, overflow := l.size()
+		size, carry := bits.Add64(m.size, n, 0)
+		m.size = size
+		m.overflow = m.overflow || overflow || carry != 0
+	}
+}
+
+func parseAtoms(src string) ([]atom, error) {
+	atoms := make([]atom, 0, len(src))
+	src = strings.ReplaceAll(src, "...", "*")
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '?':
+			atoms = append(atoms, atom{set: HexChars, min: 1, max: 1})
+		case c == '*':
+			atoms = append(atoms, atom{set: HexChars, min: 0, max: KeyLength})
+		case c == '[':
+			end := strings.IndexByte(src[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated class at %d", ErrMaskSyntax, i)
+			}
+			set, err := parseClass(src[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("%w at %d", err, i)
+			}
+			atoms = append(atoms, atom{set: set, min: 1, max: 1})
+			i += end
+		case c == '{':
+			if len(atoms) == 0 || atoms[len(atoms)-1].max != 1 {
+				return nil, fmt.Errorf("%w: quantifier without atom at %d", ErrMaskSyntax, i)
+			}
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quantifier at %d", ErrMaskSyntax, i)
+			}
+			min, max, err := parseQuantifier(src[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("%w at %d", err, i)
+			}
+			atoms[len(atoms)-1].min, atoms[len(atoms)-1].max = min, max
+			i += end
+		case strings.IndexByte(HexChars, c) >= 0:
+			atoms = append(atoms, atom{set: string(c), min: 1, max: 1})
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrMaskSyntax, c, i)
+		}
+	}
+
+	return atoms, nil
+}
+
+func parseClass(body string) (string, error) {
+	var seen [len(HexChars)]bool
+	for i := 0; i < len(body); i++ {
+		lo := strings.IndexByte(HexChars, body[i])
+		if lo < 0 {
+			return "", fmt.Errorf("%w: invalid class character %q", ErrMaskSyntax, body[i])
+		}
+		hi := lo
+		if i+2 < len(body) && body[i+1] == '-' {
+			hi = strings.IndexByte(HexChars, body[i+2])
+			if hi < lo {
+				return "", fmt.Errorf("%w: invalid class range %q", ErrMaskSyntax, body[i:i+3])
+			}
+			i += 2
+		}
+		for j := lo; j <= hi; j++ {
+			seen[j] = true
+		}
+	}
+
+	var set strings.Builder
+	for j, ok := range seen {
+		if ok {
+			set.WriteByte(HexChars[j])
+		}
+	}
+	if set.Len() == 0 {
+		return "", fmt.Errorf("%w: empty class", ErrMaskSyntax)
+	}
+
+	return set.String(), nil
+}
+
+func parseQuantifier(body string) (int, int, error) {
+	lo, hi, ranged := strings.Cut(body, ",")
+	min, err := strconv.Atoi(lo)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: invalid quantifier %q", ErrMaskSyntax, body)
+	}
+	max := min
+	if ranged {
+		if max, err = strconv.Atoi(hi); err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid quantifier %q", ErrMaskSyntax, body)
+		}
+	}
+	if min < 0 || max < min || max > KeyLength {
+		return 0, 0, fmt.Errorf("%w: invalid quantifier %q", ErrMaskSyntax, body)
+	}
+
+	return min, max, nil
+}
+
+// expand appends a layout for every combination of repeat counts, choosing
+// shorter repeats of earlier atoms first.
+func (m *Mask) expand(atoms []atom, counts []int, i, left int) error {
+	if i == len(atoms) {
+		if left != 0 {
+			return nil
+		}
+		if len(m.layouts) == maxLayouts {
+			return fmt.Errorf("%w: more than %d layouts", ErrMaskSyntax, maxLayouts)
+		}
+
+		l := make(layout, 0, m.Length)
+		for j, a := range atoms {
+			for n := 0; n < counts[j]; n++ {
+				l = append(l, a.set)
+			}
+		}
+		m.layouts = append(m.layouts, l)
+		return nil
+	}
+
+	rest := 0
+	for _, a := range atoms[i+1:] {
+		rest += a.min
+	}
+	for n := atoms[i].min; n <= atoms[i].max && n+rest <= left; n++ {
+		counts[i] = n
+		if err := m.expand(atoms, counts, i+1, left-n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dedupe finds the earlier layouts overlapping each layout and counts the
+// candidates they share.
+func (m *Mask) dedupe() {
+	masks := make([][]uint16, len(m.layouts))
+	for i, l := range m.layouts {
+		masks[i] = l.bitsets()
+	}
+
+	m.shadows = make([][]shadow, len(m.layouts))
+	m.counted = true
+	terms := 0
+	for l := range m.layouts {
+		var shared [][]uint16
+		for k := 0; k < l; k++ {
+			sh, both, ok := overlap(masks[l], masks[k])
+			if !ok {
+				continue
+			}
+			m.shadows[l] = append(m.shadows[l], sh)
+			shared = append(shared, both)
+		}
+		if m.counted && len(shared) > 0 {
+			n, ok := unionSize(shared, &terms)
+			m.duplicates += n
+			m.counted = ok
+		}
+	}
+	if !m.counted {
+		m.duplicates = 0
+	}
+}
+
+// overlap returns the shadow earlier casts on later and the product set of
+// the candidates they share, or false when they share none.
+func overlap(later, earlier []uint16) (shadow, []uint16, bool) {
+	var sh shadow
+	both := make([]uint16, len(later))
+	for p := range later {
+		both[p] = later[p] & earlier[p]
+		if both[p] == 0 {
+			return shadow{}, nil, false
+		}
+		if both[p] != later[p] {
+			sh.pos = append(sh.pos, p)
+			sh.sets = append(sh.sets, earlier[p])
+		}
+	}
+	return sh, both, true
+}
+
+// unionSize counts the candidates in the union of the product sets by
+// inclusion-exclusion, |A ∪ R| = |A| + |R| - |(A ∩ R)|, skipping empty
+// intersections. Every set is a subset of one layout, so the result fits
+// in 64 bits and wrapping intermediate sums do not matter. It gives up
+// once terms exceeds maxTerms.
+func unionSize(sets [][]uint16, terms *int) (uint64, bool) {
+	if len(sets) == 0 {
+		return 0, true
+	}
+	if *terms++; *terms > maxTerms {
+		return 0, false
+	}
+
+	first := productSize(sets[0])
+	rest, ok := unionSize(sets[1:], terms)
+	if !ok {
+		return 0, false
+	}
+
+	var shared [][]uint16
+	for _, s := range sets[1:] {
+		both := make([]uint16, len(s))
+		empty := false
+		for p := range s {
+			both[p] = sets[0][p] & s[p]
+			empty = empty || both[p] == 0
+		}
+		if !empty {
+			shared = append(shared, both)
+		}
+	}
+	common, ok := unionSize(shared, terms)
+	if !ok {
+		return 0, false
+	}
+
+	return first + rest - common, true
+}
+
+func productSize(set []uint16) uint64 {
+	n := uint64(1)
+	for _, b := range set {
+		n *= uint64(bits.OnesCount16(b))
+	}
+	return n
+}
+
+func (l layout) bitsets() []uint16 {
+	masks := make([]uint16, len(l))
+	for p, set := range l {
+		for i := 0; i < len(set); i++ {
+			masks[p] |= 1 << strings.IndexByte(HexChars, set[i])
+		}
+	}
+	return masks
+}
+
+// Duplicates returns how many indexes hold a key that an earlier layout
+// already yielded. It reports false when the layouts overlap in too many
+// ways to count them.
+func (m *Mask) Duplicates() (uint64, bool) {
+	return m.duplicates, m.counted
+}
+
+// Layout is the part of a mask keyspace with fixed repeat counts: Sets[p]
+// is the character set of key position p and candidates First up to
+// First+Size-1 walk Sets like an odometer.
+type Layout struct {
+	First     uint64
+	Size      uint64
+	Sets      []string
+	duplicate func(key []byte) bool
+}
+
+func (m *Mask) Layouts() []Layout {
+	layouts := make([]Layout, len(m.layouts))
+	for i := range m.layouts {
+		layouts[i] = m.layout(i)
+	}
+	return layouts
+}
+
+// EachLayout calls fn with the layouts holding the indexes [from, to) in
+// order until fn returns false.
+func (m *Mask) EachLayout(from, to uint64, fn func(Layout) bool) {
+	to = min(to, m.size)
+	if from >= to {
+		return
+	}
+	l := sort.Search(len(m.offsets), func(j int) bool { return m.offsets[j] > from }) - 1
+	for ; l < len(m.layouts) && m.offsets[l] < to; l++ {
+		if !fn(m.layout(l)) {
+			return
+		}
+	}
+}
+
+func (m *Mask) layout(i int) Layout {
+	n, _ := m.layouts[i].size()
+	l := Layout{First: m.offsets[i], Size: n, Sets: m.layouts[i]}
+	if m.shadows != nil && len(m.shadows[i]) > 0 {
+		shadows := m.shadows[i]
+		l.duplicate = func(key []byte) bool { return shadowed(shadows, key) }
+	}
+	return l
+}
+
+// Overlapped reports whether any earlier layout shares candidates with this
+// one, that is whether Duplicate can ever be true.
+func (l Layout) Overlapped() bool {
+	return l.duplicate != nil
+}
+
+// Duplicate reports whether the candidate key of this layout, in lowercase
+// hex, was already yielded by an earlier layout.
+func (l Layout) Duplicate(key []byte) bool {
+	return l.duplicate != nil && l.duplicate(key)
+}
+
+func shadowed(shadows []shadow, key []byte) bool {
+next:
+	for _, sh := range shadows {
+		for j, p := range sh.pos {
+			if sh.sets[j]&(1<<strings.IndexByte(HexChars, key[p])) == 0 {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (m *Mask) Size() uint64 {
+	return m.size
+}
+
+// At returns the candidate at i, and false when an earlier layout already
+// yielded it.
+func (m *Mask) At(i uint64) (string, bool) {
+	l, digits := m.locate(i)
+	buf := make([]byte, len(m.layouts[l]))
+	for p, set := range m.layouts[l] {
+		buf[p] = set[digits[p]]
+	}
+	return string(buf), m.shadows == nil || !shadowed(m.shadows[l], buf)
+}
+
+// Each calls fn with the candidates at [from, to), layout by layout, each
+// layout walked like an odometer with the last position changing fastest.
+// Duplicates of candidates of earlier layouts are skipped.
+func (m *Mask) Each(from, to uint64, fn func(i uint64, key string) bool) {
+	to = min(to, m.size)
+	if from >= to {
+		return
+	}
+
+	l, digits := m.locate(from)
+	buf := make([]byte, len(m.layouts[l]))
+	for p, set := range m.layouts[l] {
+		buf[p] = set[digits[p]]
+	}
+
+	for i := from; ; {
+		if m.shadows == nil || !shadowed(m.shadows[l], buf) {
+			if !fn(i, string(buf)) {
+				return
+			}
+		}
+		if i++; i == to {
+			return
+		}
+
+		if !m.layouts[l].next(buf, digits) {
+			l++
+			for p, set := range m.layouts[l] {
+				buf[p] = set[0]
+				digits[p] = 0
+			}
+		}
+	}
+}
+
+// locate returns the layout holding index i and the character index of
+// every position of that candidate.
+func (m *Mask) locate(i uint64) (int, []int) {
+	l := sort.Search(len(m.offsets), func(j int) bool { return m.offsets[j] > i }) - 1
+	i -= m.offsets[l]
+
+	layout := m.layouts[l]
+	digits := make([]int, len(layout))
+	for p := len(layout) - 1; p >= 0; p-- {
+		n := uint64(len(layout[p]))
+		digits[p] = int(i % n)
+		i /= n
+	}
+	return l, digits
+}
+
+// next advances buf to the following candidate of the layout and reports
+// false once the layout wraps around.
+func (l layout) next(buf []byte, digits []int) bool {
+	for p := len(l) - 1; p >= 0; p-- {
+		set := l[p]
+		digits[p]++
+		if digits[p] < len(set) {
+			buf[p] = set[digits[p]]
+			return true
+		}
+		digits[p] = 0
+		buf[p] = set[0]
+	}
+	return false
+}
+
+func (l layout) size() (uint64, bool) {
+	n := uint64(1)
+	for _, set := range l {
+		hi, lo := bits.Mul64(n, uint64(len(set)))
+		if hi != 0 {
+			return 0, true
+		}
+		n = lo
+	}
+	return n, false
+}
+
+// Match reports whether the lowercase hex string s fits any layout.
+func (m *Mask) Match(s string) bool {
+	for _, l := range m.layouts {
+		if l.match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l layout) match(s string) bool {
+	if len(s) != len(l) {
+		return false
+	}
+	for p, set := range l {
+		if strings.IndexByte(set, s[p]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Bits returns how many bits of a uniformly random hex string the mask pins
+// down, that is -log2 of the chance that such a string matches. Overlapping
+// layouts are counted separately, which errs on the side of fewer bits.
+func (m *Mask) Bits() float64 {
+	chance := 0.0
+	for _, l := range m.layouts {
+		p := 1.0
+		for _, set := range l {
+			p *= float64(len(set)) / float64(len(HexChars))
+		}
+		chance += p
+	}
+	return -math.Log2(math.Min(chance, 1))
+}
@@ -0,0 +1,56 @@
+package generator
+
+import "testing"
+
+// eachAgreesWithAt checks the Keyspace contract: Each yields exactly the
+// indexes At reports, with the same candidates.
+func eachAgreesWithAt(t *testing.T, ks Keyspace, from, to uint64) {
+	t.Helper()
+	next := from
+	skip := func(upto uint64) {
+		for ; next < upto; next++ {
+			if c, ok := ks.At(next); ok {
+				t.Fatalf("Each skips %d, but At gives %s", next, c)
+			}
+		}
+	}
+	ks.Each(from, to, func(i uint64, key string) bool {
+		skip(i)
+		if c, ok := ks.At(i); !ok || c != key {
+			t.Fatalf("Each gives %s at %d, At %s, %t", key, i, c, ok)
+		}
+		next = i + 1
+		return true
+	})
+	skip(min(to, ks.Size()))
+}
+
+func TestMaskAtSkipsDuplicates(t *testing.T) {
+	m, err := ParseMask("0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5[0-7]*0*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dups, _ := m.Duplicates()
+	if dups == 0 {
+		t.Fatal("mask has no duplicates to skip")
+	}
+	eachAgreesWithAt(t, m, 0, m.Size())
+
+	skipped := uint64(0)
+	for i := uint64(0); i < m.Size(); i++ {
+		if _, ok := m.At(i); !ok {
+			skipped++
+		}
+	}
+	if skipped != dups {
+		t.Fatalf("At skips %d indexes, Duplicates counts %d", skipped, dups)
+	}
+}
+
+func TestTyposAt(t *testing.T) {
+	ty, err := NewTypos("0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5a6b01", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eachAgreesWithAt(t, ty, 0, ty.Size())
+}
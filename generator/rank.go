@@ -0,0 +1,479 @@
+package generator
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// endBias is how much more likely every extra missing character at the
+// preferred end of the key makes a layout.
+const endBias = 2
+
+// Confidence is what the owner remembers about a key beyond its mask.
+type Confidence struct {
+	// Prefer maps key positions, counted from 1 at the left, to the
+	// characters they most likely hold.
+	Prefer map[int]Preference
+	// MissingAt is "start" or "end" when the unknown characters are more
+	// likely at that end of the key, or empty when either is as likely.
+	MissingAt string
+}
+
+// Preference says a position holds one of Chars with probability Chance.
+type Preference struct {
+	Chars  string
+	Chance float64
+}
+
+// ParsePreference parses "position=chars" or "position=chars@chance", e.g.
+// "12=b6@0.8". The chance defaults to 0.9.
+func ParsePreference(s string) (int, Preference, error) {
+	pos, rest, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, Preference{}, fmt.Errorf("preference %q: want position=chars[@chance]", s)
+	}
+	p, err := strconv.Atoi(pos)
+	if err != nil || p < 1 || p > KeyLength {
+		return 0, Preference{}, fmt.Errorf("preference %q: position must be 1 to %d", s, KeyLength)
+	}
+
+	pref := Preference{Chance: 0.9}
+	chars, chance, ok := strings.Cut(rest, "@")
+	if ok {
+		if pref.Chance, err = strconv.ParseFloat(chance, 64); err != nil || pref.Chance <= 0 || pref.Chance >= 1 {
+			return 0, Preference{}, fmt.Errorf("preference %q: chance must be between 0 and 1", s)
+		}
+	}
+	pref.Chars = strings.ToLower(chars)
+	if pref.Chars == "" || !isHex(pref.Chars) {
+		return 0, Preference{}, fmt.Errorf("preference %q: want hex characters", s)
+	}
+
+	return p, pref, nil
+}
+
+// Rank returns the keyspace of m reordered so that candidates come in
+// descending probability under c. Every preferred position splits a
+// layout into the part holding a preferred character and the rest; all
+// candidates of such a part are equally likely, so the ranked keyspace
+// visits the parts most likely first, popping them off a priority queue
+// only as its indexes are reached. It holds exactly the candidates of m.
+func (m *Mask) Rank(c Confidence) (*Ranked, error) {
+	switch c.MissingAt {
+	case "", "start", "end":
+	default:
+		return nil, fmt.Errorf("missing at %q: want start or end", c.MissingAt)
+	}
+
+	r := &Ranked{mask: m}
+	masks := make([][]uint16, len(m.layouts))
+	for l, lay := range m.layouts {
+		s := classify(lay, c)
+		r.queue.layouts = append(r.queue.layouts, s)
+		heap.Push(&r.queue, part{layout: l, last: -1, chance: s.chance(0)})
+		masks[l] = lay.bitsets()
+	}
+
+	// A candidate shared by two layouts comes in whichever of its two parts
+	// ranks first, so every layout needs the overlaps in both directions.
+	r.rivals = make([][]rival, len(m.layouts))
+	for l := range m.layouts {
+		for k := range m.layouts {
+			if k == l {
+				continue
+			}
+			if sh, _, ok := overlap(masks[l], masks[k]); ok {
+				r.rivals[l] = append(r.rivals[l], rival{layout: k, shadow: sh})
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Ranked is the keyspace of a mask ordered by Mask.Rank. Its parts are
+// found lazily, so the number of preferred positions is not bounded by
+// what fits in memory up front, only by how far the search gets. A Ranked
+// is safe for concurrent use.
+type Ranked struct {
+	mask   *Mask
+	rivals [][]rival
+
+	mu    sync.Mutex
+	queue partQueue
+	// parts are the parts popped so far, in order; end is the index
+	// following the last of them.
+	parts []rankedPart
+	end   uint64
+}
+
+type rankedPart struct {
+	first  uint64
+	layout int
+	other  uint64
+}
+
+// rival is another layout sharing candidates with a layout; shadow tells
+// which of them.
+type rival struct {
+	layout int
+	shadow shadow
+}
+
+func (r *Ranked) Size() uint64 {
+	return r.mask.size
+}
+
+// Duplicates counts the candidates shared by layouts, as in the mask.
+func (r *Ranked) Duplicates() (uint64, bool) {
+	return r.mask.Duplicates()
+}
+
+// find returns the index of the part holding index i, popping parts until
+// it is found. i must be less than Size.
+func (r *Ranked) find(i uint64) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.end <= i {
+		r.pop()
+	}
+	return sort.Search(len(r.parts), func(j int) bool { return r.parts[j].first > i }) - 1
+}
+
+// part returns the j-th part, popping parts until it is found, or false
+// past the last part.
+func (r *Ranked) part(j int) (rankedPart, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.parts) <= j && r.queue.Len() > 0 {
+		r.pop()
+	}
+	if j >= len(r.parts) {
+		return rankedPart{}, false
+	}
+	return r.parts[j], true
+}
+
+func (r *Ranked) pop() {
+	p := heap.Pop(&r.queue).(part)
+	s := r.queue.layouts[p.layout]
+	r.parts = append(r.parts, rankedPart{first: r.end, layout: p.layout, other: p.other})
+	n, _ := s.build(p.other).size()
+	r.end += n
+
+	// Positions move to their other class in order of increasing drop, a
+	// part adding the next one or trading its last for the next one, so
+	// every part is reached exactly once and after the parts ranking
+	// before it.
+	next := p.last + 1
+	if next == len(s.order) {
+		return
+	}
+	add := p.other | 1<<s.order[next]
+	heap.Push(&r.queue, part{layout: p.layout, other: add, last: next, chance: s.chance(add)})
+	if p.last >= 0 {
+		trade := add &^ (1 << s.order[p.last])
+		heap.Push(&r.queue, part{layout: p.layout, other: trade, last: next, chance: s.chance(trade)})
+	}
+}
+
+// layout returns the j-th part as a layout of the ranked keyspace, or
+// false past the last part.
+func (r *Ranked) layout(j int) (Layout, bool) {
+	p, ok := r.part(j)
+	if !ok {
+		return Layout{}, false
+	}
+	s := r.queue.layouts[p.layout]
+	sets := s.build(p.other)
+	n, _ := sets.size()
+	l := Layout{First: p.first, Size: n, Sets: sets}
+	if len(r.rivals[p.layout]) > 0 {
+		chance := s.chance(p.other)
+		l.duplicate = func(key []byte) bool { return r.duplicate(p.layout, p.other, chance, key) }
+	}
+	return l, true
+}
+
+// duplicate reports whether key, a candidate of the part of layout l with
+// the given other classes and chance, also lies in a part of another
+// layout that ranks before it.
+func (r *Ranked) duplicate(l int, other uint64, chance float64, key []byte) bool {
+	for _, rv := range r.rivals[l] {
+		if !shadowed([]shadow{rv.shadow}, key) {
+			continue
+		}
+		s := r.queue.layouts[rv.layout]
+		theirs := s.classOf(key)
+		if r.queue.before(part{layout: rv.layout, other: theirs, chance: s.chance(theirs)}, part{layout: l, other: other, chance: chance}) {
+			return true
+		}
+	}
+	return false
+}
+
+// EachLayout calls fn with the parts holding the indexes [from, to), as
+// layouts, in order until fn returns false.
+func (r *Ranked) EachLayout(from, to uint64, fn func(Layout) bool) {
+	to = min(to, r.Size())
+	if from >= to {
+		return
+	}
+	for j := r.find(from); ; j++ {
+		l, ok := r.layout(j)
+		if !ok || l.First >= to || !fn(l) {
+			return
+		}
+	}
+}
+
+func (r *Ranked) At(i uint64) (string, bool) {
+	if i >= r.Size() {
+		return "", false
+	}
+	l, _ := r.layout(r.find(i))
+	buf := make([]byte, len(l.Sets))
+	rest := i - l.First
+	for p := len(l.Sets) - 1; p >= 0; p-- {
+		n := uint64(len(l.Sets[p]))
+		buf[p] = l.Sets[p][rest%n]
+		rest /= n
+	}
+	return string(buf), !l.Duplicate(buf)
+}
+
+// Each calls fn with the candidates at [from, to), part by part, skipping
+// the copies of candidates a part ranking earlier already yielded.
+func (r *Ranked) Each(from, to uint64, fn func(i uint64, key string) bool) {
+	r.EachLayout(from, to, func(l Layout) bool {
+		lo, hi := max(from, l.First), min(to, l.First+l.Size)
+		digits := make([]int, len(l.Sets))
+		buf := make([]byte, len(l.Sets))
+		rest := lo - l.First
+		for p := len(l.Sets) - 1; p >= 0; p-- {
+			n := uint64(len(l.Sets[p]))
+			digits[p] = int(rest % n)
+			buf[p] = l.Sets[p][digits[p]]
+			rest /= n
+		}
+		for i := lo; i < hi; i++ {
+			if !l.Duplicate(buf) && !fn(i, string(buf)) {
+				return false
+			}
+			layout(l.Sets).next(buf, digits)
+		}
+		return true
+	})
+}
+
+// classified is a layout whose preferred positions are divided into a likely
+// and an unlikely class of characters. base is the log probability of a
+// candidate with every position in its likely class.
+type classified struct {
+	layout    layout
+	positions []classes
+	base      float64
+	// order lists the positions by increasing drop, ties rightmost first.
+	order []int
+}
+
+type classes struct {
+	pos           int
+	likely, other string
+	// drop is the log probability lost by moving to the other class.
+	drop float64
+}
+
+func classify(l layout, c Confidence) classified {
+	s := classified{layout: l}
+	for _, set := range l {
+		s.base -= math.Log(float64(len(set)))
+	}
+
+	switch c.MissingAt {
+	case "start":
+		s.base += math.Log(endBias) * float64(l.leading())
+	case "end":
+		s.base += math.Log(endBias) * float64(l.trailing())
+	}
+
+	for p, set := range l {
+		pref, ok := c.Prefer[p+1]
+		if !ok {
+			continue
+		}
+		var in, out strings.Builder
+		for i := 0; i < len(set); i++ {
+			if strings.IndexByte(pref.Chars, set[i]) >= 0 {
+				in.WriteByte(set[i])
+			} else {
+				out.WriteByte(set[i])
+			}
+		}
+		if in.Len() == 0 || out.Len() == 0 {
+			continue
+		}
+
+		// Undo the uniform 1/len(set) counted above.
+		s.base += math.Log(float64(len(set)))
+		cl := classes{pos: p, likely: in.String(), other: out.String()}
+		inChance := math.Log(pref.Chance / float64(in.Len()))
+		outChance := math.Log((1 - pref.Chance) / float64(out.Len()))
+		if outChance > inChance {
+			cl.likely, cl.other = cl.other, cl.likely
+			inChance, outChance = outChance, inChance
+		}
+		s.base += inChance
+		cl.drop = outChance - inChance
+		s.positions = append(s.positions, cl)
+	}
+
+	for j := range s.positions {
+		s.order = append(s.order, j)
+	}
+	sort.SliceStable(s.order, func(a, b int) bool {
+		x, y := s.positions[s.order[a]], s.positions[s.order[b]]
+		if x.drop != y.drop {
+			return x.drop > y.drop
+		}
+		return x.pos > y.pos
+	})
+
+	return s
+}
+
+// chance returns the log probability of a candidate of the part where
+// position j holds its other class when bit j of other is set. The drops
+// are always added in the same order, so a part has one chance however it
+// was reached.
+func (s classified) chance(other uint64) float64 {
+	c := s.base
+	for j, cl := range s.positions {
+		if other&(1<<j) != 0 {
+			c += cl.drop
+		}
+	}
+	return c
+}
+
+// classOf returns which positions of key, a candidate of the layout, hold
+// their other class.
+func (s classified) classOf(key []byte) uint64 {
+	var other uint64
+	for j, cl := range s.positions {
+		if strings.IndexByte(cl.other, key[cl.pos]) >= 0 {
+			other |= 1 << j
+		}
+	}
+	return other
+}
+
+// build returns the part of the layout where position j holds its other
+// class when bit j of other is set and its likely class otherwise.
+func (s classified) build(other uint64) layout {
+	l := append(layout(nil), s.layout...)
+	for j, cl := range s.positions {
+		l[cl.pos] = cl.likely
+		if other&(1<<j) != 0 {
+			l[cl.pos] = cl.other
+		}
+	}
+	return l
+}
+
+// leading returns how many positions before the first fixed character vary.
+func (l layout) leading() int {
+	n := 0
+	for n < len(l) && len(l[n]) > 1 {
+		n++
+	}
+	return n
+}
+
+// trailing returns how many positions after the last fixed character vary.
+func (l layout) trailing() int {
+	n := 0
+	for n < len(l) && len(l[len(l)-1-n]) > 1 {
+		n++
+	}
+	return n
+}
+
+// part is the part of a layout whose positions set in other hold their
+// other class. last indexes the position of the layout's order that moved
+// last, -1 when none did.
+type part struct {
+	layout int
+	other  uint64
+	last   int
+	chance float64
+}
+
+// partQueue pops the most likely part first, ties broken by layout and
+// then by which positions moved, so the ranking is deterministic.
+type partQueue struct {
+	layouts []classified
+	parts   []part
+}
+
+func (q *partQueue) Len() int { return len(q.parts) }
+
+func (q *partQueue) Less(i, j int) bool { return q.before(q.parts[i], q.parts[j]) }
+
+func (q *partQueue) before(a, b part) bool {
+	if a.chance != b.chance {
+		return a.chance > b.chance
+	}
+	if a.layout != b.layout {
+		return a.layout < b.layout
+	}
+	return lessBits(a.other, b.other)
+}
+
+func (q *partQueue) Swap(i, j int) { q.parts[i], q.parts[j] = q.parts[j], q.parts[i] }
+
+func (q *partQueue) Push(x any) { q.parts = append(q.parts, x.(part)) }
+
+func (q *partQueue) Pop() any {
+	p := q.parts[len(q.parts)-1]
+	q.parts = q.parts[:len(q.parts)-1]
+	return p
+}
+
+// lessBits orders the parts of one layout by which positions moved to
+// their other class, leftmost moves last.
+func lessBits(a, b uint64) bool {
+	if a == b {
+		return false
+	}
+	return b>>bits.TrailingZeros64(a^b)&1 != 0
+}
+
+// String returns c in a stable form, e.g. "prefer=12:b6@0.9;missing-at=start",
+// so that it can take part in a checkpoint spec.
+func (c Confidence) String() string {
+	var parts []string
+	if len(c.Prefer) > 0 {
+		positions := make([]int, 0, len(c.Prefer))
+		for p := range c.Prefer {
+			positions = append(positions, p)
+		}
+		sort.Ints(positions)
+
+		prefs := make([]string, len(positions))
+		for i, p := range positions {
+			prefs[i] = fmt.Sprintf("%d:%s@%g", p, c.Prefer[p].Chars, c.Prefer[p].Chance)
+		}
+		parts = append(parts, "prefer="+strings.Join(prefs, ","))
+	}
+	if c.MissingAt != "" {
+		parts = append(parts, "missing-at="+c.MissingAt)
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+const fragment = "0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5a6b01"
+
+func TestRankHoldsMask(t *testing.T) {
+	m, err := ParseMask(fragment[:59] + "[0-7]*0*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := m.Rank(Confidence{
+		Prefer:    map[int]Preference{61: {"0", 0.6}, 62: {"12", 0.8}, 64: {"0", 0.7}},
+		MissingAt: "end",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eachAgreesWithAt(t, r, 0, r.Size())
+
+	want := map[string]bool{}
+	m.Each(0, m.Size(), func(_ uint64, k string) bool {
+		want[k] = true
+		return true
+	})
+	got := map[string]bool{}
+	r.Each(0, r.Size(), func(i uint64, k string) bool {
+		if got[k] || !want[k] {
+			t.Fatalf("ranked candidate %d: %s repeated or not in the mask", i, k)
+		}
+		got[k] = true
+		return true
+	})
+	if len(got) != len(want) {
+		t.Fatalf("ranked keyspace holds %d candidates, mask %d", len(got), len(want))
+	}
+}
+
+// Every preferred position doubles the parts of a layout; they are only
+// found as the search reaches them.
+func TestRankManyPreferences(t *testing.T) {
+	m, err := ParseMask(fragment[:49] + strings.Repeat("?", 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Confidence{Prefer: map[int]Preference{}}
+	for p := 50; p <= KeyLength; p++ {
+		c.Prefer[p] = Preference{Chars: fragment[p-1 : p], Chance: 0.7}
+	}
+	r, err := m.Rank(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, ok := r.At(0); !ok || k != fragment {
+		t.Fatalf("most likely candidate %s, want %s", k, fragment)
+	}
+
+	// Candidates with one miss come next, fewer misses always first.
+	misses := 0
+	r.Each(0, 1<<16, func(i uint64, k string) bool {
+		n := 0
+		for p := 49; p < KeyLength; p++ {
+			if k[p] != fragment[p] {
+				n++
+			}
+		}
+		if n < misses {
+			t.Fatalf("candidate %d: %s misses %d preferences after one missing %d", i, k, n, misses)
+		}
+		misses = n
+		return true
+	})
+	if misses < 2 {
+		t.Fatalf("first candidates miss at most %d preferences", misses)
+	}
+	eachAgreesWithAt(t, r, 1<<16-100, 1<<16+100)
+}
@@ -0,0 +1,27 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrKeyLength = errors.New("known part and missing characters do not add up to key length")
+
+// NewSplit compiles the mask distributing missing hex characters between the
+// start and the end of a known key fragment. Candidates come in the same
+// order as the former generate_key_variants Ruby script: all prefix-less keys
+// first.
+func NewSplit(known string, missing int) (*Mask, error) {
+	known = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(known), "0x"))
+	for _, c := range known {
+		if !strings.ContainsRune(HexChars, c) {
+			return nil, fmt.Errorf("known part: invalid hex character %q", c)
+		}
+	}
+	if missing < 0 || len(known)+missing != KeyLength {
+		return nil, fmt.Errorf("%w: %d + %d != %d", ErrKeyLength, len(known), missing, KeyLength)
+	}
+
+	return ParseMask(fmt.Sprintf("?{0,%d}%s?{0,%d}", missing, known, missing))
+}
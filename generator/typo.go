@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxShift = 8
+
+// MaxEdits bounds the edit distance of Typos. Variants are enumerated up
+// front to rank them, about 330 thousand at distance 2; distance 3 runs to
+// hundreds of millions, more than fits in memory.
+const MaxEdits = 2
+
+// Relative likelihoods of the transcription errors a variant undoes.
+const (
+	weightLookalike = 0.4
+	weightSwap      = 0.25
+	weightDouble    = 0.15
+	weightDrop      = 0.1
+	weightShift     = 0.1
+)
+
+// lookalikes lists what a written character may really have been. Non-hex
+// letters come from keys copied by hand and always need a substitution.
+var lookalikes = map[byte]string{
+	'0': "8d", '1': "7", '3': "8", '5': "6", '6': "5b", '7': "1",
+	'8': "03b", 'a': "d", 'b': "68", 'c': "e", 'd': "0a", 'e': "c",
+	'o': "0", 'l': "1", 'i': "1", 's': "5", 'g': "9", 'q': "9", 'z': "2",
+}
+
+// Typos enumerates the keys within MaxEdits transcription errors of a
+// written key, most likely first. Each edit undoes one adjacent swap,
+// look-alike substitution, dropped character, doubled character or block
+// shifted by one position.
+type Typos struct {
+	Written    string
+	MaxEdits   int
+	variants   []variant
+	duplicates uint64
+}
+
+type variant struct {
+	key    string
+	weight float64
+}
+
+func NewTypos(written string, maxEdits int) (*Typos, error) {
+	written = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(written), "0x"))
+	if maxEdits < 0 {
+		return nil, fmt.Errorf("typos: negative edit distance %d", maxEdits)
+	}
+	if maxEdits > MaxEdits {
+		return nil, fmt.Errorf("typos: %d edits is more than the %d that can be ranked in memory; search further with -mask", maxEdits, MaxEdits)
+	}
+	if d := len(written) - KeyLength; d > maxEdits || -d > maxEdits {
+		return nil, fmt.Errorf("typos: %d characters is more than %d edits away from a key", len(written), maxEdits)
+	}
+
+	t := &Typos{Written: written, MaxEdits: maxEdits}
+	seen := make(map[string]float64)
+	t.walk(written, maxEdits, 1, seen)
+
+	t.variants = make([]variant, 0, len(seen))
+	for key, weight := range seen {
+		t.variants = append(t.variants, variant{key: key, weight: weight})
+	}
+	sort.Slice(t.variants, func(i, j int) bool {
+		if t.variants[i].weight != t.variants[j].weight {
+			return t.variants[i].weight > t.variants[j].weight
+		}
+		return t.variants[i].key < t.variants[j].key
+	})
+
+	return t, nil
+}
+
+// walk records s if it is a well-formed key and recurses into every single
+// edit of s while edits are left, keeping the best weight of each key.
+func (t *Typos) walk(s string, left int, weight float64, seen map[string]float64) {
+	if len(s) == KeyLength && isHex(s) {
+		best, ok := seen[s]
+		if ok {
+			t.duplicates++
+		}
+		if weight > best {
+			seen[s] = weight
+		}
+	}
+	if left == 0 {
+		return
+	}
+
+	d := len(s) - KeyLength
+	visit := func(next string, w float64) {
+		if nd := len(next) - KeyLength; nd > left-1 || -nd > left-1 {
+			return
+		}
+		t.walk(next, left-1, weight*w, seen)
+	}
+
+	for i := 0; i < len(s); i++ {
+		for _, c := range []byte(lookalikes[s[i]]) {
+			visit(s[:i]+string(c)+s[i+1:], weightLookalike)
+		}
+	}
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] != s[i+1] {
+			visit(s[:i]+s[i+1:i+2]+s[i:i+1]+s[i+2:], weightSwap)
+		}
+	}
+	if d > -left {
+		for i := 1; i < len(s); i++ {
+			if s[i] == s[i-1] && (i == 1 || s[i] != s[i-2]) {
+				visit(s[:i]+s[i+1:], weightDouble)
+			}
+		}
+	}
+	if d < left {
+		for i := 0; i <= len(s); i++ {
+			for c := 0; c < len(HexChars); c++ {
+				visit(s[:i]+HexChars[c:c+1]+s[i:], weightDrop/float64(len(HexChars)))
+			}
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		for j := i + 3; j <= len(s) && j-i <= maxShift; j++ {
+			visit(s[:i]+s[i+1:j]+s[i:i+1]+s[j:], weightShift)
+			visit(s[:i]+s[j-1:j]+s[i:j-1]+s[j:], weightShift)
+		}
+	}
+}
+
+func (t *Typos) Size() uint64 {
+	return uint64(len(t.variants))
+}
+
+func (t *Typos) At(i uint64) (string, bool) {
+	return t.variants[i].key, true
+}
+
+// Duplicates returns how many times an edit sequence led to a variant that
+// an earlier sequence had already produced.
+func (t *Typos) Duplicates() (uint64, bool) {
+	return t.duplicates, true
+}
+
+// Each calls fn with the variants at [from, to) in descending likelihood.
+func (t *Typos) Each(from, to uint64, fn func(i uint64, key string) bool) {
+	for i := from; i < min(to, t.Size()); i++ {
+		if !fn(i, t.variants[i].key) {
+			return
+		}
+	}
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(HexChars, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
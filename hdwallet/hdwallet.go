@@ -0,0 +1,102 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+// Hardened is added to a child index to derive a hardened child.
+const Hardened = 1 << 31
+
+// ErrInvalidChild is returned for the rare index whose derived key is not a
+// valid scalar; BIP32 wallets skip such an index.
+var ErrInvalidChild = errors.New("derived key is invalid, skip this index")
+
+// Key is a BIP32 extended private key.
+type Key struct {
+	Private key.Private
+	Chain   [32]byte
+}
+
+// Master derives the master key of a BIP32 seed.
+func Master(seed []byte) (*Key, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := &Key{}
+	copy(k.Private[:], sum[:32])
+	copy(k.Chain[:], sum[32:])
+	if err := k.Private.Validate(); err != nil {
+		return nil, fmt.Errorf("master key: %w", ErrInvalidChild)
+	}
+	return k, nil
+}
+
+// Child derives the child key at index i, hardened when i >= Hardened.
+func (k *Key) Child(i uint32) (*Key, error) {
+	var data [37]byte
+	if i >= Hardened {
+		copy(data[1:33], k.Private[:])
+	} else {
+		var priv secp256k1.ModNScalar
+		priv.SetBytes((*[32]byte)(&k.Private))
+		var pub secp256k1.JacobianPoint
+		secp256k1.ScalarBaseMultNonConst(&priv, &pub)
+		pub.ToAffine()
+		copy(data[:33], secp256k1.NewPublicKey(&pub.X, &pub.Y).SerializeCompressed())
+	}
+	binary.BigEndian.PutUint32(data[33:], i)
+
+	mac := hmac.New(sha512.New, k.Chain[:])
+	mac.Write(data[:])
+	sum := mac.Sum(nil)
+
+	var tweak, priv secp256k1.ModNScalar
+	if tweak.SetByteSlice(sum[:32]) {
+		return nil, ErrInvalidChild
+	}
+	priv.SetBytes((*[32]byte)(&k.Private))
+	priv.Add(&tweak)
+	if priv.IsZero() {
+		return nil, ErrInvalidChild
+	}
+
+	child := &Key{Private: priv.Bytes()}
+	copy(child.Chain[:], sum[32:])
+	return child, nil
+}
+
+// Derive follows path from k.
+func (k *Key) Derive(path Path) (*Key, error) {
+	var err error
+	for _, i := range path {
+		if k, err = k.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Path is a BIP32 derivation path below the master key.
+type Path []uint32
+
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteByte('m')
+	for _, i := range p {
+		if i >= Hardened {
+			fmt.Fprintf(&b, "/%d'", i-Hardened)
+		} else {
+			fmt.Fprintf(&b, "/%d", i)
+		}
+	}
+	return b.String()
+}
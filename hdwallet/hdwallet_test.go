@@ -0,0 +1,68 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Test vector 1 of BIP32, the private keys and chain codes of its
+// extended keys.
+func TestVector1(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	steps := []struct {
+		path        string
+		child       uint32
+		priv, chain string
+	}{
+		{"m", 0,
+			"e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			"873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"},
+		{"m/0'", Hardened + 0,
+			"edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+			"47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{"m/0'/1", 1,
+			"3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+			"2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+		{"m/0'/1/2'", Hardened + 2,
+			"cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+			"04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f"},
+		{"m/0'/1/2'/2", 2,
+			"0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4",
+			"cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd"},
+		{"m/0'/1/2'/2/1000000000", 1000000000,
+			"471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8",
+			"c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e"},
+	}
+
+	k, err := Master(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var path Path
+	for i, s := range steps {
+		if i > 0 {
+			if k, err = k.Child(s.child); err != nil {
+				t.Fatalf("%s: %v", s.path, err)
+			}
+			path = append(path, s.child)
+		}
+		if path.String() != s.path {
+			t.Fatalf("path %s, want %s", path, s.path)
+		}
+		if got := hex.EncodeToString(k.Private[:]); got != s.priv {
+			t.Errorf("%s: private key %s, want %s", s.path, got, s.priv)
+		}
+		if got := hex.EncodeToString(k.Chain[:]); got != s.chain {
+			t.Errorf("%s: chain code %s, want %s", s.path, got, s.chain)
+		}
+	}
+
+	master, _ := Master(seed)
+	derived, err := master.Derive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *derived != *k {
+		t.Errorf("Derive(%s) differs from deriving child by child", path)
+	}
+}
@@ -0,0 +1,190 @@
+package hdwallet
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Presets are the derivation paths of common Ethereum wallets. {a} stands
+// for the account and {i} for the address index.
+var Presets = map[string]string{
+	// MetaMask, Trust, Coinbase Wallet and most software wallets.
+	"metamask": "m/44'/60'/0'/0/{i}",
+	// BIP44 with accounts, as Trezor and MyCrypto number them.
+	"bip44": "m/44'/60'/{a}'/0/{i}",
+	// Ledger Live, one account per address.
+	"ledger-live": "m/44'/60'/{a}'/0/0",
+	// Legacy MyEtherWallet and the Ledger Chrome app.
+	"mew": "m/44'/60'/0'/{i}",
+}
+
+// MaxPaths bounds how many paths Expand returns. Every candidate derives
+// every path, so a search over more is better split by -indexes.
+const MaxPaths = 4096
+
+// Range is the inclusive range of child indexes [From, To].
+type Range struct {
+	From, To uint32
+}
+
+// ParseRange parses "n" or "from-to".
+func ParseRange(s string) (Range, error) {
+	lo, hi, ranged := strings.Cut(s, "-")
+	from, err := strconv.ParseUint(lo, 10, 31)
+	if err != nil {
+		return Range{}, fmt.Errorf("range %q: %w", s, err)
+	}
+	to := from
+	if ranged {
+		if to, err = strconv.ParseUint(hi, 10, 31); err != nil {
+			return Range{}, fmt.Errorf("range %q: %w", s, err)
+		}
+	}
+	if to < from {
+		return Range{}, fmt.Errorf("range %q: empty", s)
+	}
+	return Range{From: uint32(from), To: uint32(to)}, nil
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// Expand turns a comma-separated list of path templates and preset names
+// into the distinct paths they cover, in order. "all" stands for every
+// preset. In a template, {a} takes every account and {i} every index of
+// the given ranges, and {n-m} every index from n to m; a trailing ' makes
+// any of them hardened, e.g. m/44'/60'/{a}'/0/{0-9}. Templates covering
+// more than MaxPaths paths together are rejected.
+func Expand(templates string, accounts, indexes Range) ([]Path, error) {
+	var sources []string
+	for _, t := range strings.Split(templates, ",") {
+		t = strings.TrimSpace(t)
+		switch {
+		case t == "all":
+			names := make([]string, 0, len(Presets))
+			for name := range Presets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				sources = append(sources, Presets[name])
+			}
+		case Presets[t] != "":
+			sources = append(sources, Presets[t])
+		default:
+			sources = append(sources, t)
+		}
+	}
+
+	var paths []Path
+	seen := make(map[string]bool)
+	total := uint64(0)
+	for _, src := range sources {
+		levels, err := parseTemplate(src, accounts, indexes)
+		if err != nil {
+			return nil, err
+		}
+		if total += count(levels); total > MaxPaths {
+			return nil, fmt.Errorf("paths %q: more than %d paths", templates, MaxPaths)
+		}
+		expand(levels, nil, func(p Path) {
+			if s := p.String(); !seen[s] {
+				seen[s] = true
+				paths = append(paths, p)
+			}
+		})
+	}
+	return paths, nil
+}
+
+func parseTemplate(src string, accounts, indexes Range) ([]Range, error) {
+	parts := strings.Split(src, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("path %q: must start with m or be one of the presets", src)
+	}
+
+	levels := make([]Range, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		body := strings.TrimRight(part, "'h")
+
+		var r Range
+		var err error
+		switch {
+		case body == "{a}":
+			r = accounts
+		case body == "{i}":
+			r = indexes
+		case strings.HasPrefix(body, "{") && strings.HasSuffix(body, "}"):
+			r, err = ParseRange(body[1 : len(body)-1])
+		default:
+			r, err = ParseRange(body)
+			if strings.Contains(body, "-") {
+				err = fmt.Errorf("ranges need braces")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("path %q: invalid index %q: %w", src, part, err)
+		}
+		if hardened {
+			r.From += Hardened
+			r.To += Hardened
+		}
+		levels = append(levels, r)
+	}
+	return levels, nil
+}
+
+// count returns how many paths levels cover, at most MaxPaths+1.
+func count(levels []Range) uint64 {
+	n := uint64(1)
+	for _, r := range levels {
+		n = min(n*(uint64(r.To-r.From)+1), MaxPaths+1)
+	}
+	return n
+}
+
+func expand(levels []Range, prefix Path, fn func(Path)) {
+	if len(levels) == 0 {
+		fn(append(Path(nil), prefix...))
+		return
+	}
+	for i := levels[0].From; ; i++ {
+		expand(levels[1:], append(prefix, i), fn)
+		if i == levels[0].To {
+			return
+		}
+	}
+}
+
+// DeriveAll derives the key at every path from k, deriving the parents the
+// paths share only once. The key of a path that runs into an invalid
+// child is nil, with its error in errs.
+func (k *Key) DeriveAll(paths []Path) ([]*Key, []error) {
+	keys := make([]*Key, len(paths))
+	errs := make([]error, len(paths))
+	cache := map[string]*Key{"m": k}
+	for n, path := range paths {
+		parent := k
+		for depth := range path {
+			prefix := path[:depth+1].String()
+			child, ok := cache[prefix]
+			if !ok {
+				var err error
+				if child, err = parent.Child(path[depth]); err != nil {
+					errs[n] = fmt.Errorf("%s: %w", path, err)
+					break
+				}
+				cache[prefix] = child
+			}
+			parent = child
+		}
+		if errs[n] == nil {
+			keys[n] = parent
+		}
+	}
+	return keys, errs
+}
@@ -0,0 +1,118 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want Range
+	}{
+		{"0", Range{0, 0}},
+		{"7", Range{7, 7}},
+		{"0-9", Range{0, 9}},
+		{"2147483647", Range{Hardened - 1, Hardened - 1}},
+	} {
+		if got, err := ParseRange(tc.s); err != nil || got != tc.want {
+			t.Errorf("ParseRange(%q) = %v, %v, want %v", tc.s, got, err, tc.want)
+		}
+	}
+	for _, s := range []string{"", "-1", "a", "3-1", "2147483648", "0-2147483648", "1-"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("ParseRange(%q) succeeds", s)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	accounts, indexes := Range{0, 1}, Range{0, 2}
+	for _, tc := range []struct {
+		templates string
+		want      []string
+	}{
+		{"metamask", []string{"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/1", "m/44'/60'/0'/0/2"}},
+		{"ledger-live", []string{"m/44'/60'/0'/0/0", "m/44'/60'/1'/0/0"}},
+		{"mew", []string{"m/44'/60'/0'/0", "m/44'/60'/0'/1", "m/44'/60'/0'/2"}},
+		{"bip44", []string{
+			"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/1", "m/44'/60'/0'/0/2",
+			"m/44'/60'/1'/0/0", "m/44'/60'/1'/0/1", "m/44'/60'/1'/0/2",
+		}},
+		// Presets in name order, each path once.
+		{"all", []string{
+			"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/1", "m/44'/60'/0'/0/2",
+			"m/44'/60'/1'/0/0", "m/44'/60'/1'/0/1", "m/44'/60'/1'/0/2",
+			"m/44'/60'/0'/0", "m/44'/60'/0'/1", "m/44'/60'/0'/2",
+		}},
+		{"m/44h/60h/{a}h/0/{5-6}", []string{
+			"m/44'/60'/0'/0/5", "m/44'/60'/0'/0/6", "m/44'/60'/1'/0/5", "m/44'/60'/1'/0/6",
+		}},
+		{"m/{i}', m/0/{a}", []string{"m/0'", "m/1'", "m/2'", "m/0/0", "m/0/1"}},
+		{"m", []string{"m"}},
+		{"m/{2147483647}'", []string{"m/2147483647'"}},
+	} {
+		paths, err := Expand(tc.templates, accounts, indexes)
+		if err != nil {
+			t.Errorf("Expand(%q): %v", tc.templates, err)
+			continue
+		}
+		got := make([]string, len(paths))
+		for i, p := range paths {
+			got[i] = p.String()
+		}
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("Expand(%q) = %q, want %q", tc.templates, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"44'/60'/0'/0/0",
+		"m/0-3",
+		"m/{3-1}",
+		"m/x",
+		"m/2147483648",
+		"ledger",
+		// Far more paths than any search derives for every candidate.
+		"m/{0-2147483647}",
+		"m/{0-99}/{0-99}",
+		"m/{0-4095}, m/1/{0}",
+	} {
+		if _, err := Expand(bad, accounts, indexes); err == nil {
+			t.Errorf("Expand(%q) succeeds", bad)
+		}
+	}
+	if paths, err := Expand("m/{0-4095}", accounts, indexes); err != nil || len(paths) != MaxPaths {
+		t.Errorf("Expand of MaxPaths paths: %d, %v", len(paths), err)
+	}
+}
+
+func TestDeriveAll(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := Master(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := Expand("m/0'/1/{0-3}', m/0'/{0-1}", Range{}, Range{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, errs := master.DeriveAll(paths)
+	for i, p := range paths {
+		if errs[i] != nil {
+			t.Fatalf("%s: %v", p, errs[i])
+		}
+		want, err := master.Derive(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *keys[i] != *want {
+			t.Errorf("%s: DeriveAll and Derive differ", p)
+		}
+	}
+	// m/0'/1/2' of BIP32 test vector 1.
+	if got := hex.EncodeToString(keys[2].Private[:]); got != "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca" {
+		t.Errorf("m/0'/1/2': private key %s", got)
+	}
+}
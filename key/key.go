@@ -0,0 +1,101 @@
+package key
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const Length = 32
+
+var (
+	ErrLength = errors.New("private key: wrong length")
+	ErrHex    = errors.New("private key: invalid hex")
+	ErrZero   = errors.New("private key: zero scalar")
+	ErrRange  = errors.New("private key: scalar not below secp256k1 order")
+)
+
+// order is the secp256k1 group order N.
+var order = [Length]byte{
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe,
+	0xba, 0xae, 0xdc, 0xe6, 0xaf, 0x48, 0xa0, 0x3b,
+	0xbf, 0xd2, 0x5e, 0x8c, 0xd0, 0x36, 0x41, 0x41,
+}
+
+// Private is a big-endian secp256k1 scalar known to be in [1, N).
+type Private [Length]byte
+
+// Parse decodes a 64 character hex private key, with or without 0x.
+func Parse(s string) (Private, error) {
+	var k Private
+
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "0x")
+	if len(raw) != 2*Length {
+		return k, fmt.Errorf("%w: %d hex characters", ErrLength, len(raw))
+	}
+	if _, err := hex.Decode(k[:], []byte(raw)); err != nil {
+		return k, ErrHex
+	}
+
+	return k, k.Validate()
+}
+
+// FromBytes copies a 32 byte big-endian scalar.
+func FromBytes(b []byte) (Private, error) {
+	var k Private
+	if len(b) != Length {
+		return k, fmt.Errorf("%w: %d bytes", ErrLength, len(b))
+	}
+	copy(k[:], b)
+
+	return k, k.Validate()
+}
+
+// Validate checks that k is a usable scalar: neither zero nor at or above
+// the group order.
+func (k *Private) Validate() error {
+	if *k == (Private{}) {
+		return ErrZero
+	}
+	if bytes.Compare(k[:], order[:]) >= 0 {
+		return ErrRange
+	}
+	return nil
+}
+
+func (k Private) Hex() string {
+	return hex.EncodeToString(k[:])
+}
+
+// LineError ties a rejected candidate to its line in the input.
+type LineError struct {
+	Line  int
+	Input string
+	Err   error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %q: %v", e.Line, e.Input, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// IndexError ties a rejected candidate to its index in a keyspace.
+type IndexError struct {
+	Index uint64
+	Input string
+	Err   error
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("candidate %d: %q: %v", e.Index, e.Input, e.Err)
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
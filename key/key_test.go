@@ -0,0 +1,65 @@
+package key
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	const valid = "0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5a6b01"
+	for _, tc := range []struct {
+		in   string
+		want error
+	}{
+		{valid, nil},
+		{"0x" + valid, nil},
+		{" " + valid + "\r\n", nil},
+		{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", nil},
+		{valid[:63], ErrLength},
+		{valid + "0", ErrLength},
+		{"zz" + valid[2:], ErrHex},
+		{strings.Repeat("0", 64), ErrZero},
+		{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", ErrRange},
+		{strings.Repeat("f", 64), ErrRange},
+	} {
+		k, err := Parse(tc.in)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tc.in, err, tc.want)
+		}
+		if err == nil && k.Hex() != strings.TrimPrefix(strings.TrimSpace(tc.in), "0x") {
+			t.Errorf("Parse(%q).Hex() = %s", tc.in, k.Hex())
+		}
+	}
+}
+
+func TestFromBytes(t *testing.T) {
+	if _, err := FromBytes(make([]byte, 31)); !errors.Is(err, ErrLength) {
+		t.Errorf("31 bytes: %v, want %v", err, ErrLength)
+	}
+	b := make([]byte, Length)
+	if _, err := FromBytes(b); !errors.Is(err, ErrZero) {
+		t.Errorf("zero: %v, want %v", err, ErrZero)
+	}
+	b[Length-1] = 1
+	if k, err := FromBytes(b); err != nil || k[Length-1] != 1 {
+		t.Errorf("one: %x, %v", k, err)
+	}
+}
+
+func TestPositionErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{&LineError{Line: 3, Input: "0a5c", Err: ErrLength}, `line 3: "0a5c": private key: wrong length`},
+		{&IndexError{Index: 17, Input: "", Err: ErrZero}, `candidate 17: "": private key: zero scalar`},
+	} {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("Error() = %s, want %s", got, tc.want)
+		}
+		if errors.Is(tc.err, ErrHex) || !errors.Is(tc.err, errors.Unwrap(tc.err)) {
+			t.Errorf("%v does not unwrap to its cause alone", tc.err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/seithhq/crypto-finder/key"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// ErrPassword is returned by Unlock when the MAC does not match, that
+	// is for every wrong password.
+	ErrPassword = errors.New("wrong password")
+	ErrFormat   = errors.New("not a version 3 keystore")
+)
+
+// Keystore is a Web3 Secret Storage (version 3) key file, as written by
+// geth, MyEtherWallet and most wallets as UTC--<date>--<address>.json.
+type Keystore struct {
+	// Address is the lowercase hex address the file claims to hold,
+	// empty when it does not say.
+	Address string
+	// KDF describes the key derivation, e.g. "scrypt n=262144 r=8 p=1".
+	KDF string
+
+	derive     func(password []byte) ([]byte, error)
+	iv         []byte
+	ciphertext []byte
+	mac        []byte
+}
+
+type file struct {
+	Address string          `json:"address"`
+	Crypto  *cryptoJSON     `json:"crypto"`
+	Legacy  *cryptoJSON     `json:"Crypto"`
+	Version json.RawMessage `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string `json:"cipher"`
+	CipherText   string `json:"ciphertext"`
+	CipherParams struct {
+		IV string `json:"iv"`
+	} `json:"cipherparams"`
+	KDF       string `json:"kdf"`
+	KDFParams struct {
+		DKLen int    `json:"dklen"`
+		Salt  string `json:"salt"`
+		N     int    `json:"n"`
+		R     int    `json:"r"`
+		P     int    `json:"p"`
+		C     int    `json:"c"`
+		PRF   string `json:"prf"`
+	} `json:"kdfparams"`
+	MAC string `json:"mac"`
+}
+
+// Load reads a keystore file, supporting the aes-128-ctr cipher with the
+// scrypt or pbkdf2 (hmac-sha256) key derivation.
+func Load(r io.Reader) (*Keystore, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	c := f.Crypto
+	if c == nil {
+		c = f.Legacy
+	}
+	if c == nil || strings.Trim(string(f.Version), `"`) != "3" {
+		return nil, ErrFormat
+	}
+	if c.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("cipher %q not supported", c.Cipher)
+	}
+
+	k := &Keystore{Address: strings.ToLower(strings.TrimPrefix(f.Address, "0x"))}
+	var err error
+	if k.iv, err = decodeHex("iv", c.CipherParams.IV, aes.BlockSize); err != nil {
+		return nil, err
+	}
+	// Keys with leading zero bytes may be stored short, as geth once did.
+	if k.ciphertext, err = decodeHex("ciphertext", c.CipherText, -1); err != nil {
+		return nil, err
+	}
+	if len(k.ciphertext) == 0 || len(k.ciphertext) > key.Length {
+		return nil, fmt.Errorf("%w: ciphertext has %d bytes, want 1 to %d", ErrFormat, len(k.ciphertext), key.Length)
+	}
+	if k.mac, err = decodeHex("mac", c.MAC, sha256.Size); err != nil {
+		return nil, err
+	}
+	salt, err := decodeHex("salt", c.KDFParams.Salt, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	p := c.KDFParams
+	if p.DKLen < 32 {
+		return nil, fmt.Errorf("kdf dklen %d: want at least 32", p.DKLen)
+	}
+	switch c.KDF {
+	case "scrypt":
+		k.KDF = fmt.Sprintf("scrypt n=%d r=%d p=%d", p.N, p.R, p.P)
+		if p.N <= 1 || p.N&(p.N-1) != 0 || p.R < 1 || p.P < 1 || uint64(p.R)*uint64(p.P) >= 1<<30 {
+			return nil, fmt.Errorf("%s: invalid parameters", k.KDF)
+		}
+		k.derive = func(password []byte) ([]byte, error) {
+			return scrypt.Key(password, salt, p.N, p.R, p.P, p.DKLen)
+		}
+	case "pbkdf2":
+		if p.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("pbkdf2 prf %q not supported", p.PRF)
+		}
+		if p.C < 1 {
+			return nil, fmt.Errorf("pbkdf2 iterations %d: want at least 1", p.C)
+		}
+		k.KDF = fmt.Sprintf("pbkdf2 c=%d", p.C)
+		k.derive = func(password []byte) ([]byte, error) {
+			return pbkdf2.Key(password, salt, p.C, p.DKLen, sha256.New), nil
+		}
+	default:
+		return nil, fmt.Errorf("kdf %q not supported", c.KDF)
+	}
+
+	return k, nil
+}
+
+func decodeHex(name, s string, length int) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrFormat, name, err)
+	}
+	if length >= 0 && len(b) != length {
+		return nil, fmt.Errorf("%w: %s has %d bytes, want %d", ErrFormat, name, len(b), length)
+	}
+	return b, nil
+}
+
+// Unlock derives the key of password and checks it against the MAC, the
+// Keccak-256 of the second half of the derived key and the ciphertext.
+// Only then is the private key decrypted.
+func (k *Keystore) Unlock(password string) (key.Private, error) {
+	derived, err := k.derive([]byte(password))
+	if err != nil {
+		return key.Private{}, err
+	}
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(derived[16:32])
+	h.Write(k.ciphertext)
+	if !bytes.Equal(h.Sum(nil), k.mac) {
+		return key.Private{}, ErrPassword
+	}
+
+	block, err := aes.NewCipher(derived[:16])
+	if err != nil {
+		return key.Private{}, err
+	}
+	var prv key.Private
+	cipher.NewCTR(block, k.iv).XORKeyStream(prv[key.Length-len(k.ciphertext):], k.ciphertext)
+	return prv, prv.Validate()
+}
@@ -0,0 +1,70 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/v3_test_vector.json is go-ethereum's
+// accounts/keystore/testdata/v3_test_vector.json.
+func TestVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/v3_test_vector.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors map[string]struct {
+		JSON     json.RawMessage
+		Password string
+		Priv     string
+	}
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"wikipage_test_vector_scrypt", "wikipage_test_vector_pbkdf2", "31_byte_key", "30_byte_key"} {
+		t.Run(name, func(t *testing.T) {
+			v, ok := vectors[name]
+			if !ok {
+				t.Fatal("no such vector")
+			}
+			k, err := Load(bytes.NewReader(v.JSON))
+			if err != nil {
+				t.Fatal(err)
+			}
+			prv, err := k.Unlock(v.Password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fmt.Sprintf("%064s", v.Priv); prv.Hex() != want {
+				t.Fatalf("Unlock = %s, want %s", prv.Hex(), want)
+			}
+			if _, err := k.Unlock(v.Password + "x"); !errors.Is(err, ErrPassword) {
+				t.Fatalf("wrong password: %v, want %v", err, ErrPassword)
+			}
+		})
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	const iv = `"cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"}`
+	for _, tc := range []struct {
+		name, json string
+		format     bool
+	}{
+		{"version", `{"version": 1, "crypto": {}}`, true},
+		{"no crypto", `{"version": 3}`, true},
+		{"cipher", `{"version": 3, "crypto": {"cipher": "aes-128-cbc"}}`, false},
+		{"empty ciphertext", `{"version": 3, "crypto": {"cipher": "aes-128-ctr", ` + iv + `, "ciphertext": ""}}`, true},
+		{"long ciphertext", `{"version": 3, "crypto": {"cipher": "aes-128-ctr", ` + iv + `, "ciphertext": "` + strings.Repeat("00", 33) + `"}}`, true},
+	} {
+		_, err := Load(strings.NewReader(tc.json))
+		if err == nil || tc.format && !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: Load = %v, want an error", tc.name, err)
+		}
+	}
+}
@@ -1,14 +1,12 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -16,71 +14,266 @@ import (
 	"math/big"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/seithhq/crypto-finder/checkpoint"
+	"github.com/seithhq/crypto-finder/generator"
+	"github.com/seithhq/crypto-finder/key"
+	"github.com/seithhq/crypto-finder/target"
 	"github.com/seithhq/crypto-finder/usdttoken"
+	"github.com/seithhq/crypto-finder/walker"
 	"golang.org/x/crypto/sha3"
 )
 
 const (
 	INFURA = "https://mainnet.infura.io/v3/8f55e3b466dc48da85e06b50177c4c0b"
+
+	// walkChunk is how many mask candidates one worker walks per batch.
+	walkChunk = 16 * walker.BlockSize
+
+	// flushIdle is how long a partial batch of streamed input may wait for
+	// more lines before it is sent anyway.
+	flushIdle = 100 * time.Millisecond
 )
 
+// targetUsage documents -target for every command taking it.
+const targetUsage = "address of the wallet being recovered, repeatable or comma-separated; a partly known address may be given as a pattern with ?, *, ..., [a-f] and {m,n} as in -mask, e.g. 0x5bec?{1,3}*65df"
+
+// backendUsage documents -backend for the search and plan.
+const backendUsage = "secp256k1 backend deriving -typo, -input, -mnemonic and password candidates: auto, decred, libsecp256k1 or purego; -mask and -known candidates are walked without one"
+
+type addressList []string
+
+func (l *addressList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *addressList) Set(value string) error {
+	*l = append(*l, target.Split(value)...)
+	return nil
+}
+
 func main() {
-	wrk := func(_ int, jobs <-chan string, results chan<- string) {
-		for private := range jobs {
-			result := fmt.Sprintf("%s;0x%s", private, addressFromPrivate(private))
-			results <- result
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bench":
+			bench(os.Args[2:])
+			return
+		case "plan":
+			plan(os.Args[2:])
+			return
+		case "vault":
+			openVault(os.Args[2:])
+			return
+		case "presale":
+			decryptPresale(os.Args[2:])
+			return
+		case "encrypt":
+			encryptFile(os.Args[2:])
+			return
+		case "decrypt":
+			decryptFile(os.Args[2:])
+			return
 		}
 	}
 
-	full := parseCombinations("storage/ruby_input.txt")
-	fmt.Println("private_key;address")
+	var candidateOpts candidateFlags
+	var targetAddresses addressList
+
+	candidateOpts.register(flag.CommandLine)
+	start := flag.Uint64("start", 0, "index of the first candidate to test")
+	count := flag.Uint64("count", 0, "number of candidates to test, 0 for all")
+	statePath := flag.String("state", "", "checkpoint file for tested ranges and hits")
+	resume := flag.Bool("resume", false, "continue the search saved in -state")
+	interval := flag.Duration("checkpoint-interval", 30*time.Second, "how often to write -state")
+	hitsPath := flag.String("hits", "", "new file to write the hits to instead of stdout, encrypted like -state")
+	encrypt := flag.String("encrypt", "", "encrypt new -state and -hits files with a key of $CRYPTO_FINDER_PASSPHRASE derived by argon2id or scrypt")
+	backendName := flag.String("backend", "auto", backendUsage)
+	workers := flag.Int("workers", runtime.NumCPU(), "number of derivation workers")
+	flag.Var(&targetAddresses, "target", targetUsage)
+	flag.Parse()
+
+	kdf, err := encryption(*encrypt)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	start := 0
-	len := len(full)
+	done := make(chan struct{})
+	var stop sync.Once
+	halt := func() { stop.Do(func() { close(done) }) }
 
-	addresses := full[start : start+len]
+	targets, err := candidateOpts.targets(targetAddresses)
+	if err != nil {
+		log.Fatal(err)
+	}
+	match, err := newMatcher(targets, halt)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	jobs := make(chan string, len)
-	results := make(chan string, len)
+	candidates, input, spec, err := candidateOpts.open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	unlock, err := candidateOpts.unlocker()
+	if err != nil {
+		log.Fatal(err)
+	}
+	be, err := pickBackend(*backendName, candidates)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	for w := 1; w <= 10; w++ {
-		go wrk(w, jobs, results)
+	end := uint64(math.MaxUint64)
+	if *count > 0 {
+		end = *start + *count
+	}
+	if candidates != nil {
+		end = min(end, candidates.Size())
 	}
 
-	for j := 0; j < len; j++ {
-		jobs <- addresses[j]
+	state := checkpoint.New(spec, *start, end)
+	switch {
+	case *resume && *statePath == "":
+		log.Fatal("-resume needs -state")
+	case *resume:
+		if state, err = checkpoint.Load(*statePath); err != nil {
+			log.Fatal(err)
+		}
+		if err := state.Check(spec, *start, end); err != nil {
+			log.Fatal(err)
+		}
+	case *statePath != "":
+		if _, err := os.Stat(*statePath); err == nil {
+			log.Fatalf("%s already exists, pass -resume to continue it", *statePath)
+		}
+	}
+	if kdf != nil {
+		state.Encryption = kdf
+	}
+	if *statePath != "" && state.Encryption == nil && candidateOpts.phrase != "" {
+		// The spec records -mnemonic and -passphrase as given.
+		log.Printf("%s will hold the mnemonic and passphrase in clear, pass -encrypt to protect them", *statePath)
 	}
-	close(jobs)
 
-	for a := 0; a < len; a++ {
-		fmt.Println(<-results)
+	var out io.Writer = os.Stdout
+	var hits *storageWriter
+	if *hitsPath != "" {
+		if hits, err = createStorage(*hitsPath, state.Encryption); err != nil {
+			log.Fatal(err)
+		}
+		out = hits
 	}
-}
+	// flush seals every hit into the -hits file as it is found, rather
+	// than once a chunk of them has accumulated.
+	flush := func() {
+		if hits != nil {
+			if err := hits.Flush(); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	fmt.Fprintln(out, candidateOpts.header())
 
-func parseCombinations(filename string) []string {
-	addresses := make([]string, 0)
+	for _, h := range state.Hits {
+		printHit(out, h)
+		if addr, err := target.ParseAddress(h.Address); err == nil && match.targets.Contains(addr) {
+			match.targets.Hit(addr)
+		}
+	}
+	flush()
+	match.tested.Store(state.Tested())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		// A second signal kills a slow drain.
+		signal.Stop(signals)
+		log.Println("interrupted, finishing queued candidates, interrupt again to quit now")
+		halt()
+	}()
+
+	pending := state.Pending()
+	if len(pending) == 0 || match.targets.Done() || (candidateOpts.decrypts() && len(state.Hits) > 0) {
+		halt()
+	}
 
-	file, err := os.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+	var walk *walker.Walker
+	var feed func(func(batch) bool)
+	switch ks := candidates.(type) {
+	case generator.Layered:
+		walk = walker.New(ks)
+		feed = feedWalk(pending, done)
+	case nil:
+		feed = feedLines(input, pending, done)
+	default:
+		feed = feedKeyspace(ks, unlock != nil, pending, done)
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
+	results := runPool(max(*workers, 1), feed, newWorker(be, walk, unlock, match))
 
-	for scanner.Scan() {
-		text := scanner.Text()
-		addresses = append(addresses, strings.Trim(text, " "))
+	save := func() {
+		if *statePath == "" {
+			return
+		}
+		if err := state.Save(*statePath); err != nil {
+			log.Println(err)
+		}
 	}
 
-	return addresses
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	skipped := uint64(0)
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				save()
+				if hits != nil {
+					if err := hits.Close(); err != nil {
+						log.Println(err)
+					}
+				}
+				if skipped > 0 {
+					log.Printf("skipped %d duplicate candidates", skipped)
+				}
+				if candidateOpts.vault != "" && len(state.Hits) > 0 {
+					log.Printf("password %q opens the vault, the vault subcommand lists its seed phrase", state.Hits[0].Secret)
+				}
+				return
+			}
+			skipped += r.skipped
+			for _, err := range r.errs {
+				log.Println(err)
+			}
+			for _, h := range r.hits {
+				printHit(out, h)
+				flush()
+				if !match.all || candidateOpts.decrypts() {
+					state.AddHit(h)
+				}
+				candidateOpts.confirm(h)
+				if candidateOpts.decrypts() {
+					// Only the right password decrypts.
+					halt()
+				}
+			}
+			state.Complete(r.index, r.to)
+		case <-ticker.C:
+			save()
+		}
+	}
 }
 
 func getBalance(client *ethclient.Client, rawAddress string) string {
@@ -206,11 +399,11 @@ func BytesToBig(data []byte) *big.Int {
 	return n
 }
 
-func ToECDSA(prv []byte) *ecdsa.PrivateKey {
+func ToECDSA(prv key.Private) *ecdsa.PrivateKey {
 	priv := new(ecdsa.PrivateKey)
 	priv.PublicKey.Curve = secp256k1.S256()
-	priv.D = BytesToBig(prv)
-	priv.PublicKey.X, priv.PublicKey.Y = secp256k1.S256().ScalarBaseMult(prv)
+	priv.D = BytesToBig(prv[:])
+	priv.PublicKey.X, priv.PublicKey.Y = secp256k1.S256().ScalarBaseMult(prv[:])
 
 	return priv
 }
@@ -223,34 +416,17 @@ func Keccak256(data ...[]byte) []byte {
 	return d.Sum(nil)
 }
 
-func decryptAES(encryptedString, keyString, nonceString string) (string, error) {
-	key, _ := hex.DecodeString(keyString)
-	enc, _ := hex.DecodeString(encryptedString)
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	aesGCM, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := len(nonceString)
-	nonce, ciphertext := enc[:nonceSize], enc[nonceSize:]
-
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
+func addressFromPrivate(privateKey string) (string, error) {
+	prv, err := key.Parse(privateKey)
 	if err != nil {
 		return "", err
 	}
 
-	return string(plaintext), nil
+	return addressFromKey(prv), nil
 }
 
-func addressFromPrivate(privateKey string) string {
-	hexBuf, _ := hex.DecodeString(privateKey)
-	priv := ToECDSA(hexBuf)
+func addressFromKey(prv key.Private) string {
+	priv := ToECDSA(prv)
 	pub := priv.PublicKey
 
 	ecdsaPubBytes := elliptic.Marshal(secp256k1.S256(), pub.X, pub.Y)
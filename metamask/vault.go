@@ -0,0 +1,206 @@
+package metamask
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/seithhq/crypto-finder/key"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// DefaultIterations is the PBKDF2 work of vaults written before MetaMask
+// recorded it in keyMetadata.
+const DefaultIterations = 10000
+
+var (
+	// ErrPassword is returned by Unlock when the AES-GCM tag does not
+	// verify, that is for every wrong password.
+	ErrPassword = errors.New("wrong password")
+	ErrFormat   = errors.New("not a MetaMask vault")
+)
+
+// Vault is the encrypted keyring store of the MetaMask extension, the
+// "vault" of its KeyringController state: the keyrings as JSON, encrypted
+// with AES-256-GCM under a PBKDF2-SHA256 key of the password.
+type Vault struct {
+	Iterations int
+
+	data, iv, salt []byte
+}
+
+// Keyring is one decrypted keyring of a vault.
+type Keyring struct {
+	Type string
+	// Mnemonic, HDPath and Accounts describe an "HD Key Tree": its first
+	// Accounts addresses are HDPath/0, HDPath/1 and so on.
+	Mnemonic string
+	HDPath   string
+	Accounts int
+	// Keys are the imported keys of a "Simple Key Pair" keyring.
+	Keys []key.Private
+}
+
+type vaultJSON struct {
+	// Vault holds the vault as a JSON string when it is given in its
+	// KeyringController state, {"vault": "{\"data\": ...}"}.
+	Vault       string `json:"vault"`
+	Data        string `json:"data"`
+	IV          string `json:"iv"`
+	Salt        string `json:"salt"`
+	KeyMetadata *struct {
+		Algorithm string `json:"algorithm"`
+		Params    struct {
+			Iterations int `json:"iterations"`
+		} `json:"params"`
+	} `json:"keyMetadata"`
+}
+
+// Load reads a vault as MetaMask stores it: {"data", "iv", "salt"} in
+// base64, with "keyMetadata" holding the PBKDF2 iterations since 2023.
+func Load(r io.Reader) (*Vault, error) {
+	var j vaultJSON
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	if j.Data == "" && j.Vault != "" {
+		if err := json.Unmarshal([]byte(j.Vault), &j); err != nil {
+			return nil, fmt.Errorf("%w: vault: %w", ErrFormat, err)
+		}
+	}
+	if j.Data == "" || j.IV == "" || j.Salt == "" {
+		return nil, fmt.Errorf("%w: want data, iv and salt", ErrFormat)
+	}
+
+	v := &Vault{Iterations: DefaultIterations}
+	if m := j.KeyMetadata; m != nil {
+		if m.Algorithm != "PBKDF2" {
+			return nil, fmt.Errorf("key derivation %q not supported", m.Algorithm)
+		}
+		v.Iterations = m.Params.Iterations
+	}
+	if v.Iterations < 1 {
+		return nil, fmt.Errorf("%w: %d iterations", ErrFormat, v.Iterations)
+	}
+
+	var err error
+	if v.data, err = base64.StdEncoding.DecodeString(j.Data); err != nil {
+		return nil, fmt.Errorf("%w: data: %w", ErrFormat, err)
+	}
+	if v.iv, err = base64.StdEncoding.DecodeString(j.IV); err != nil {
+		return nil, fmt.Errorf("%w: iv: %w", ErrFormat, err)
+	}
+	if v.salt, err = base64.StdEncoding.DecodeString(j.Salt); err != nil {
+		return nil, fmt.Errorf("%w: salt: %w", ErrFormat, err)
+	}
+	if len(v.iv) == 0 || len(v.data) < 16 {
+		return nil, fmt.Errorf("%w: iv or data too short", ErrFormat)
+	}
+	return v, nil
+}
+
+// Decrypt returns the plaintext of the vault, the keyrings as JSON.
+func (v *Vault) Decrypt(password string) ([]byte, error) {
+	k := pbkdf2.Key([]byte(password), v.salt, v.Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	// MetaMask uses 16 byte IVs, not the 12 bytes of a standard GCM nonce.
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(v.iv))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, v.iv, v.data, nil)
+	if err != nil {
+		return nil, ErrPassword
+	}
+	return plain, nil
+}
+
+// Unlock decrypts the vault and decodes its keyrings.
+func (v *Vault) Unlock(password string) ([]Keyring, error) {
+	plain, err := v.Decrypt(password)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyrings(plain)
+}
+
+type keyringJSON struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type hdJSON struct {
+	Mnemonic json.RawMessage `json:"mnemonic"`
+	Accounts int             `json:"numberOfAccounts"`
+	HDPath   string          `json:"hdPath"`
+}
+
+// ParseKeyrings decodes the keyrings of a decrypted vault. Keyrings of
+// other types, like hardware wallets, hold no secret and keep only their
+// type.
+func ParseKeyrings(plain []byte) ([]Keyring, error) {
+	var raw []keyringJSON
+	if err := json.Unmarshal(plain, &raw); err != nil {
+		return nil, fmt.Errorf("keyrings: %w", err)
+	}
+
+	keyrings := make([]Keyring, 0, len(raw))
+	for _, r := range raw {
+		k := Keyring{Type: r.Type}
+		switch r.Type {
+		case "HD Key Tree":
+			var hd hdJSON
+			if err := json.Unmarshal(r.Data, &hd); err != nil {
+				return nil, fmt.Errorf("%s keyring: %w", r.Type, err)
+			}
+			mnemonic, err := decodeMnemonic(hd.Mnemonic)
+			if err != nil {
+				return nil, fmt.Errorf("%s keyring: %w", r.Type, err)
+			}
+			k.Mnemonic, k.Accounts, k.HDPath = mnemonic, hd.Accounts, hd.HDPath
+			if k.HDPath == "" {
+				k.HDPath = "m/44'/60'/0'/0"
+			}
+		case "Simple Key Pair":
+			var hexKeys []string
+			if err := json.Unmarshal(r.Data, &hexKeys); err != nil {
+				return nil, fmt.Errorf("%s keyring: %w", r.Type, err)
+			}
+			for _, h := range hexKeys {
+				prv, err := key.Parse(h)
+				if err != nil {
+					return nil, fmt.Errorf("%s keyring: %w", r.Type, err)
+				}
+				k.Keys = append(k.Keys, prv)
+			}
+		}
+		keyrings = append(keyrings, k)
+	}
+	return keyrings, nil
+}
+
+// decodeMnemonic accepts the mnemonic as a string or, as newer versions
+// store it, as an array of its UTF-8 bytes.
+func decodeMnemonic(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var b []byte
+	var codes []int
+	if err := json.Unmarshal(raw, &codes); err != nil {
+		return "", fmt.Errorf("mnemonic: %w", err)
+	}
+	for _, c := range codes {
+		b = append(b, byte(c))
+	}
+	return string(b), nil
+}
@@ -0,0 +1,105 @@
+package metamask
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/seithhq/crypto-finder/key"
+)
+
+// The vaults in testdata were encrypted with WebCrypto the way
+// @metamask/browser-passworder does it, under the password below.
+// vault.json is a current vault, with keyMetadata and the mnemonic as an
+// array of bytes; state.json holds an older vault, without keyMetadata and
+// with the mnemonic as a string, nested in its KeyringController state.
+const password = "correct horse battery staple"
+
+const phrase = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestUnlock(t *testing.T) {
+	imported, err := key.Parse("0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5a6b01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		file       string
+		iterations int
+		want       []Keyring
+	}{
+		{"vault.json", 600000, []Keyring{
+			{Type: "HD Key Tree", Mnemonic: phrase, HDPath: "m/44'/60'/0'/0", Accounts: 2},
+			{Type: "Simple Key Pair", Keys: []key.Private{imported}},
+			{Type: "Ledger Hardware"},
+		}},
+		{"state.json", DefaultIterations, []Keyring{
+			{Type: "HD Key Tree", Mnemonic: phrase, HDPath: "m/44'/60'/0'/0", Accounts: 1},
+		}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			file, err := os.Open("testdata/" + tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			v, err := Load(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Iterations != tc.iterations {
+				t.Errorf("Iterations = %d, want %d", v.Iterations, tc.iterations)
+			}
+			if len(v.iv) != 16 {
+				t.Errorf("IV has %d bytes, want 16", len(v.iv))
+			}
+
+			keyrings, err := v.Unlock(password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(keyrings, tc.want) {
+				t.Errorf("Unlock = %+v, want %+v", keyrings, tc.want)
+			}
+			if _, err := v.Unlock(password + "x"); !errors.Is(err, ErrPassword) {
+				t.Errorf("wrong password: %v, want %v", err, ErrPassword)
+			}
+		})
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	const fields = `"data": "AAAAAAAAAAAAAAAAAAAAAA==", "iv": "AAAAAAAAAAAAAAAAAAAAAA==", "salt": "AAAA"`
+	for _, tc := range []struct {
+		name, json string
+		format     bool
+	}{
+		{"not json", `data`, true},
+		{"no salt", `{"data": "AAAAAAAAAAAAAAAAAAAAAA==", "iv": "AAAA"}`, true},
+		{"nested", `{"vault": "{\"data\": 1}"}`, true},
+		{"base64", `{"data": "!", "iv": "AAAA", "salt": "AAAA"}`, true},
+		{"short data", `{"data": "AAAA", "iv": "AAAA", "salt": "AAAA"}`, true},
+		{"iterations", `{` + fields + `, "keyMetadata": {"algorithm": "PBKDF2", "params": {"iterations": 0}}}`, true},
+		{"algorithm", `{` + fields + `, "keyMetadata": {"algorithm": "argon2id"}}`, false},
+	} {
+		_, err := Load(strings.NewReader(tc.json))
+		if err == nil || tc.format && !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: Load = %v, want an error", tc.name, err)
+		}
+	}
+}
+
+func TestParseKeyringsErrors(t *testing.T) {
+	for _, plain := range []string{
+		`{}`,
+		`[{"type": "HD Key Tree", "data": {"mnemonic": {}}}]`,
+		`[{"type": "Simple Key Pair", "data": ["0a5c"]}]`,
+	} {
+		if _, err := ParseKeyrings([]byte(plain)); err == nil {
+			t.Errorf("ParseKeyrings(%s) succeeded", plain)
+		}
+	}
+}
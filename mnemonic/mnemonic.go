@@ -0,0 +1,221 @@
+package mnemonic
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SeedLength is the length of a BIP39 seed in bytes.
+const SeedLength = 64
+
+var (
+	ErrLength = errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	ErrWord   = errors.New("no wordlist word fits")
+)
+
+// Words is the BIP39 English wordlist.
+var Words = wordlists.English
+
+var index = func() map[string]int {
+	index := make(map[string]int, len(Words))
+	for i, w := range Words {
+		index[w] = i
+	}
+	return index
+}()
+
+// Index returns the position of word in Words, or false if it is not a
+// BIP39 word.
+func Index(word string) (int, bool) {
+	i, ok := index[word]
+	return i, ok
+}
+
+// ValidLength reports whether n words make a BIP39 mnemonic.
+func ValidLength(n int) bool {
+	return n >= 12 && n <= 24 && n%3 == 0
+}
+
+// Valid reports whether the mnemonic made of the given word indexes
+// carries a correct checksum: its last len(words)/3 bits must be the first
+// bits of the SHA-256 of the entropy in front of them.
+func Valid(words []int) bool {
+	if !ValidLength(len(words)) {
+		return false
+	}
+
+	var buf [33]byte
+	n := 0
+	for _, w := range words {
+		for b := 10; b >= 0; b-- {
+			if w>>b&1 == 1 {
+				buf[n/8] |= 0x80 >> (n % 8)
+			}
+			n++
+		}
+	}
+
+	checksumBits := len(words) / 3
+	entropy := buf[:(n-checksumBits)/8]
+	sum := sha256.Sum256(entropy)
+	return sum[0]>>(8-checksumBits) == buf[len(entropy)]>>(8-checksumBits)
+}
+
+// Seed derives the BIP39 seed of a mnemonic with PBKDF2-HMAC-SHA512 over
+// 2048 rounds, salted with "mnemonic" and the optional passphrase.
+func Seed(phrase, passphrase string) []byte {
+	return pbkdf2.Key([]byte(norm.NFKD.String(phrase)), []byte("mnemonic"+norm.NFKD.String(passphrase)), 2048, SeedLength, sha512.New)
+}
+
+// Phrase enumerates the mnemonics with a valid checksum that fit a
+// remembered phrase. Every word of the phrase is one of
+//
+//	word      a BIP39 word, taken as is
+//	?         an unknown word: any of the 2048
+//	word~     a suspected typo: every word within two edits of it
+//
+// A word outside the wordlist is treated as a suspected typo, and also
+// matches the words it is a four-letter abbreviation of.
+type Phrase struct {
+	Source  string
+	choices [][]int
+	size    uint64
+}
+
+func ParsePhrase(src string) (*Phrase, error) {
+	tokens := strings.Fields(strings.ToLower(src))
+	if !ValidLength(len(tokens)) {
+		return nil, fmt.Errorf("%w, got %d", ErrLength, len(tokens))
+	}
+
+	p := &Phrase{Source: src, choices: make([][]int, len(tokens)), size: 1}
+	for i, t := range tokens {
+		p.choices[i] = choices(t)
+		if len(p.choices[i]) == 0 {
+			return nil, fmt.Errorf("word %d %q: %w", i+1, t, ErrWord)
+		}
+		if p.size > ^uint64(0)/uint64(len(p.choices[i])) {
+			return nil, fmt.Errorf("phrase %q: too many candidates", src)
+		}
+		p.size *= uint64(len(p.choices[i]))
+	}
+
+	return p, nil
+}
+
+func choices(token string) []int {
+	if token == "?" {
+		all := make([]int, len(Words))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	word, suspect := strings.CutSuffix(token, "~")
+	if i, ok := index[word]; ok && !suspect {
+		return []int{i}
+	}
+
+	var near []int
+	for i, w := range Words {
+		if distance(word, w) <= 2 || (!suspect && len(word) >= 4 && strings.HasPrefix(w, word[:4])) {
+			near = append(near, i)
+		}
+	}
+	return near
+}
+
+// distance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent swaps.
+func distance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// Size counts every combination of word choices, including the ones Each
+// skips for their checksum.
+func (p *Phrase) Size() uint64 {
+	return p.size
+}
+
+// At returns the mnemonic at i, and false when its checksum is invalid.
+func (p *Phrase) At(i uint64) (string, bool) {
+	digits := p.locate(i)
+	words := make([]int, len(digits))
+	for k, d := range digits {
+		words[k] = p.choices[k][d]
+	}
+	return p.phrase(digits), Valid(words)
+}
+
+// Each calls fn with the mnemonics at [from, to) whose checksum is valid,
+// the last word changing fastest.
+func (p *Phrase) Each(from, to uint64, fn func(i uint64, phrase string) bool) {
+	to = min(to, p.size)
+	if from >= to {
+		return
+	}
+
+	digits := p.locate(from)
+	words := make([]int, len(digits))
+	for i := from; i < to; i++ {
+		for k, d := range digits {
+			words[k] = p.choices[k][d]
+		}
+		if Valid(words) && !fn(i, p.phrase(digits)) {
+			return
+		}
+
+		for k := len(digits) - 1; k >= 0; k-- {
+			if digits[k]++; digits[k] < len(p.choices[k]) {
+				break
+			}
+			digits[k] = 0
+		}
+	}
+}
+
+func (p *Phrase) locate(i uint64) []int {
+	digits := make([]int, len(p.choices))
+	for k := len(digits) - 1; k >= 0; k-- {
+		n := uint64(len(p.choices[k]))
+		digits[k] = int(i % n)
+		i /= n
+	}
+	return digits
+}
+
+func (p *Phrase) phrase(digits []int) string {
+	words := make([]string, len(digits))
+	for k, d := range digits {
+		words[k] = Words[p.choices[k][d]]
+	}
+	return strings.Join(words, " ")
+}
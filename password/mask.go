@@ -0,0 +1,111 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMaskSyntax = errors.New("invalid password mask")
+	ErrTooLarge   = errors.New("password keyspace does not fit in 64 bits")
+)
+
+// Charsets are the hashcat built-in character sets of a password mask.
+var Charsets = map[byte]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+func init() {
+	Charsets['a'] = Charsets['l'] + Charsets['u'] + Charsets['d'] + Charsets['s']
+}
+
+// Mask is an indexable keyspace of the passwords fitting a hashcat style
+// mask: ?l, ?u, ?d, ?h, ?H, ?s and ?a stand for one character of their
+// set, ?? for a literal ? and every other character for itself. The last
+// position changes fastest.
+type Mask struct {
+	Source    string
+	positions []string
+	size      uint64
+}
+
+func ParseMask(src string) (*Mask, error) {
+	m := &Mask{Source: src, size: 1}
+	for i := 0; i < len(src); i++ {
+		set := src[i : i+1]
+		if src[i] == '?' {
+			if i+1 == len(src) {
+				return nil, fmt.Errorf("%w %q: trailing ?", ErrMaskSyntax, src)
+			}
+			i++
+			if src[i] != '?' {
+				var ok bool
+				if set, ok = Charsets[src[i]]; !ok {
+					return nil, fmt.Errorf("%w %q: unknown charset ?%c", ErrMaskSyntax, src, src[i])
+				}
+			}
+		}
+		if m.size > ^uint64(0)/uint64(len(set)) {
+			return nil, fmt.Errorf("mask %q: %w", src, ErrTooLarge)
+		}
+		m.size *= uint64(len(set))
+		m.positions = append(m.positions, set)
+	}
+	if len(m.positions) == 0 {
+		return nil, fmt.Errorf("%w: empty mask", ErrMaskSyntax)
+	}
+	return m, nil
+}
+
+func (m *Mask) Size() uint64 {
+	return m.size
+}
+
+func (m *Mask) At(i uint64) (string, bool) {
+	return m.password(m.locate(i)), true
+}
+
+func (m *Mask) Each(from, to uint64, fn func(i uint64, candidate string) bool) {
+	to = min(to, m.size)
+	if from >= to {
+		return
+	}
+
+	digits := m.locate(from)
+	for i := from; i < to; i++ {
+		if !fn(i, m.password(digits)) {
+			return
+		}
+		for k := len(digits) - 1; k >= 0; k-- {
+			if digits[k]++; digits[k] < len(m.positions[k]) {
+				break
+			}
+			digits[k] = 0
+		}
+	}
+}
+
+func (m *Mask) locate(i uint64) []int {
+	digits := make([]int, len(m.positions))
+	for k := len(digits) - 1; k >= 0; k-- {
+		n := uint64(len(m.positions[k]))
+		digits[k] = int(i % n)
+		i /= n
+	}
+	return digits
+}
+
+func (m *Mask) password(digits []int) string {
+	var b strings.Builder
+	b.Grow(len(digits))
+	for k, d := range digits {
+		b.WriteByte(m.positions[k][d])
+	}
+	return b.String()
+}
@@ -0,0 +1,419 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrRuleSyntax = errors.New("invalid rule")
+
+// Rule is a compiled hashcat rule: a chain of functions rewriting a word,
+// any of which may reject it.
+type Rule struct {
+	Source string
+	funcs  []func(word []byte) ([]byte, bool)
+}
+
+// Apply returns the candidate the rule makes of word, or false when the
+// rule rejects word.
+func (r Rule) Apply(word string) (string, bool) {
+	w := []byte(word)
+	for _, f := range r.funcs {
+		var ok bool
+		if w, ok = f(w); !ok {
+			return "", false
+		}
+	}
+	return string(w), true
+}
+
+// ParseRule compiles one line of a hashcat rule file. Supported are the
+// functions
+//
+//	:  l u c C t TN r d pN f { } $X ^X [ ] DN xNM ONM iNX oNX 'N
+//	sXY @X zN ZN q k K *NM LN RN +N -N .N ,N yN YN E eX
+//
+// and the rejections <N >N _N !X /X (X )X =NX %NX. Positions N and M
+// are 0-9 or A-Z for 10 to 35. As in hashcat, a function whose position
+// is beyond the word leaves it unchanged.
+func ParseRule(src string) (Rule, error) {
+	r := Rule{Source: src}
+	for i := 0; i < len(src); {
+		op := src[i]
+		i++
+		if op == ' ' || op == ':' {
+			continue
+		}
+		spec, ok := functions[op]
+		if !ok {
+			return Rule{}, fmt.Errorf("%w %q: unknown function %q", ErrRuleSyntax, src, op)
+		}
+		if i+len(spec.args) > len(src) {
+			return Rule{}, fmt.Errorf("%w %q: %c needs %d arguments", ErrRuleSyntax, src, op, len(spec.args))
+		}
+
+		var args [2]int
+		var chars [2]byte
+		for k, kind := range spec.args {
+			c := src[i+k]
+			if kind == 'N' {
+				n, ok := position(c)
+				if !ok {
+					return Rule{}, fmt.Errorf("%w %q: %c wants a position, got %q", ErrRuleSyntax, src, op, c)
+				}
+				args[k] = n
+			}
+			chars[k] = c
+		}
+		i += len(spec.args)
+		r.funcs = append(r.funcs, spec.build(args, chars))
+	}
+	return r, nil
+}
+
+func position(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// function describes a rule function by its arguments, N for a position
+// and X for a character, and builds it from their values.
+type function struct {
+	args  string
+	build func(n [2]int, x [2]byte) func([]byte) ([]byte, bool)
+}
+
+// rewrite builds a function that never rejects.
+func rewrite(args string, f func(w []byte, n [2]int, x [2]byte) []byte) function {
+	return function{args, func(n [2]int, x [2]byte) func([]byte) ([]byte, bool) {
+		return func(w []byte) ([]byte, bool) { return f(w, n, x), true }
+	}}
+}
+
+// reject builds a function passing w on only when keep holds.
+func reject(args string, keep func(w []byte, n [2]int, x [2]byte) bool) function {
+	return function{args, func(n [2]int, x [2]byte) func([]byte) ([]byte, bool) {
+		return func(w []byte) ([]byte, bool) { return w, keep(w, n, x) }
+	}}
+}
+
+var functions = map[byte]function{
+	'l': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte { return lower(w) }),
+	'u': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte { return upper(w) }),
+	'c': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		w = lower(w)
+		if len(w) > 0 {
+			w[0] = toUpper(w[0])
+		}
+		return w
+	}),
+	'C': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		w = upper(w)
+		if len(w) > 0 {
+			w[0] = toLower(w[0])
+		}
+		return w
+	}),
+	't': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		for i := range w {
+			w[i] = toggle(w[i])
+		}
+		return w
+	}),
+	'T': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w[n[0]] = toggle(w[n[0]])
+		}
+		return w
+	}),
+	'r': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte { return reversed(w) }),
+	'd': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte { return append(w, w...) }),
+	'p': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		out := w
+		for k := 0; k < n[0]; k++ {
+			out = append(out, w...)
+		}
+		return out
+	}),
+	'f': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte { return append(w, reversed(append([]byte(nil), w...))...) }),
+	'{': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		if len(w) > 0 {
+			w = append(w[1:], w[0])
+		}
+		return w
+	}),
+	'}': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		if len(w) > 0 {
+			w = append([]byte{w[len(w)-1]}, w[:len(w)-1]...)
+		}
+		return w
+	}),
+	'$': rewrite("X", func(w []byte, _ [2]int, x [2]byte) []byte { return append(w, x[0]) }),
+	'^': rewrite("X", func(w []byte, _ [2]int, x [2]byte) []byte { return append([]byte{x[0]}, w...) }),
+	'[': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		if len(w) > 0 {
+			w = w[1:]
+		}
+		return w
+	}),
+	']': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		if len(w) > 0 {
+			w = w[:len(w)-1]
+		}
+		return w
+	}),
+	'D': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w = append(w[:n[0]], w[n[0]+1:]...)
+		}
+		return w
+	}),
+	'x': rewrite("NN", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0]+n[1] <= len(w) {
+			w = w[n[0] : n[0]+n[1]]
+		}
+		return w
+	}),
+	'O': rewrite("NN", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0]+n[1] <= len(w) {
+			w = append(w[:n[0]], w[n[0]+n[1]:]...)
+		}
+		return w
+	}),
+	'i': rewrite("NX", func(w []byte, n [2]int, x [2]byte) []byte {
+		if n[0] <= len(w) {
+			w = append(w[:n[0]], append([]byte{x[1]}, w[n[0]:]...)...)
+		}
+		return w
+	}),
+	'o': rewrite("NX", func(w []byte, n [2]int, x [2]byte) []byte {
+		if n[0] < len(w) {
+			w[n[0]] = x[1]
+		}
+		return w
+	}),
+	'\'': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w = w[:n[0]]
+		}
+		return w
+	}),
+	's': rewrite("XX", func(w []byte, _ [2]int, x [2]byte) []byte {
+		for i := range w {
+			if w[i] == x[0] {
+				w[i] = x[1]
+			}
+		}
+		return w
+	}),
+	'@': rewrite("X", func(w []byte, _ [2]int, x [2]byte) []byte {
+		out := w[:0]
+		for _, c := range w {
+			if c != x[0] {
+				out = append(out, c)
+			}
+		}
+		return out
+	}),
+	'z': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if len(w) > 0 {
+			w = append(bytesOf(w[0], n[0]), w...)
+		}
+		return w
+	}),
+	'Z': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if len(w) > 0 {
+			w = append(w, bytesOf(w[len(w)-1], n[0])...)
+		}
+		return w
+	}),
+	'q': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		out := make([]byte, 0, 2*len(w))
+		for _, c := range w {
+			out = append(out, c, c)
+		}
+		return out
+	}),
+	'k': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		if len(w) >= 2 {
+			w[0], w[1] = w[1], w[0]
+		}
+		return w
+	}),
+	'K': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte {
+		if n := len(w); n >= 2 {
+			w[n-2], w[n-1] = w[n-1], w[n-2]
+		}
+		return w
+	}),
+	'*': rewrite("NN", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) && n[1] < len(w) {
+			w[n[0]], w[n[1]] = w[n[1]], w[n[0]]
+		}
+		return w
+	}),
+	'L': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w[n[0]] <<= 1
+		}
+		return w
+	}),
+	'R': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w[n[0]] >>= 1
+		}
+		return w
+	}),
+	'+': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w[n[0]]++
+		}
+		return w
+	}),
+	'-': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] < len(w) {
+			w[n[0]]--
+		}
+		return w
+	}),
+	'.': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0]+1 < len(w) {
+			w[n[0]] = w[n[0]+1]
+		}
+		return w
+	}),
+	',': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] >= 1 && n[0] < len(w) {
+			w[n[0]] = w[n[0]-1]
+		}
+		return w
+	}),
+	'y': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] <= len(w) {
+			w = append(append([]byte(nil), w[:n[0]]...), w...)
+		}
+		return w
+	}),
+	'Y': rewrite("N", func(w []byte, n [2]int, _ [2]byte) []byte {
+		if n[0] <= len(w) {
+			w = append(w, append([]byte(nil), w[len(w)-n[0]:]...)...)
+		}
+		return w
+	}),
+	'E': rewrite("", func(w []byte, _ [2]int, _ [2]byte) []byte { return title(w, ' ') }),
+	'e': rewrite("X", func(w []byte, _ [2]int, x [2]byte) []byte { return title(w, x[0]) }),
+
+	'<': reject("N", func(w []byte, n [2]int, _ [2]byte) bool { return len(w) <= n[0] }),
+	'>': reject("N", func(w []byte, n [2]int, _ [2]byte) bool { return len(w) >= n[0] }),
+	'_': reject("N", func(w []byte, n [2]int, _ [2]byte) bool { return len(w) == n[0] }),
+	'!': reject("X", func(w []byte, _ [2]int, x [2]byte) bool { return !strings.Contains(string(w), string(x[0])) }),
+	'/': reject("X", func(w []byte, _ [2]int, x [2]byte) bool { return strings.Contains(string(w), string(x[0])) }),
+	'(': reject("X", func(w []byte, _ [2]int, x [2]byte) bool { return len(w) > 0 && w[0] == x[0] }),
+	')': reject("X", func(w []byte, _ [2]int, x [2]byte) bool { return len(w) > 0 && w[len(w)-1] == x[0] }),
+	'=': reject("NX", func(w []byte, n [2]int, x [2]byte) bool { return n[0] < len(w) && w[n[0]] == x[1] }),
+	'%': reject("NX", func(w []byte, n [2]int, x [2]byte) bool { return strings.Count(string(w), string(x[1])) >= n[0] }),
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+func toggle(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return toUpper(c)
+	}
+	return toLower(c)
+}
+
+func lower(w []byte) []byte {
+	for i := range w {
+		w[i] = toLower(w[i])
+	}
+	return w
+}
+
+func upper(w []byte) []byte {
+	for i := range w {
+		w[i] = toUpper(w[i])
+	}
+	return w
+}
+
+func reversed(w []byte) []byte {
+	for i, j := 0, len(w)-1; i < j; i, j = i+1, j-1 {
+		w[i], w[j] = w[j], w[i]
+	}
+	return w
+}
+
+func title(w []byte, sep byte) []byte {
+	w = lower(w)
+	for i := range w {
+		if i == 0 || w[i-1] == sep {
+			w[i] = toUpper(w[i])
+		}
+	}
+	return w
+}
+
+func bytesOf(c byte, n int) []byte {
+	return []byte(strings.Repeat(string(c), n))
+}
+
+// ReadRules compiles a hashcat rule file, skipping blank lines and
+// # comments.
+func ReadRules(source string, r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, line, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules", source)
+	}
+	return rules, nil
+}
+
+// Combine chains every rule of a with every rule of b, the way hashcat
+// combines several -r files: the rules of b change fastest.
+func Combine(a, b []Rule) []Rule {
+	out := make([]Rule, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			out = append(out, Rule{
+				Source: strings.TrimSpace(x.Source + " " + y.Source),
+				funcs:  append(append([]func([]byte) ([]byte, bool)(nil), x.funcs...), y.funcs...),
+			})
+		}
+	}
+	return out
+}
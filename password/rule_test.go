@@ -0,0 +1,154 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The examples of https://hashcat.net/wiki/doku.php?id=rule_based_attack,
+// on the word p@ssW0rd unless given.
+func TestRules(t *testing.T) {
+	for _, tc := range []struct {
+		rule, word, want string
+	}{
+		{":", "", "p@ssW0rd"},
+		{"l", "", "p@ssw0rd"},
+		{"u", "", "P@SSW0RD"},
+		{"c", "", "P@ssw0rd"},
+		{"C", "", "p@SSW0RD"},
+		{"t", "", "P@SSw0RD"},
+		{"T3", "", "p@sSW0rd"},
+		{"r", "", "dr0Wss@p"},
+		{"d", "", "p@ssW0rdp@ssW0rd"},
+		{"p2", "", "p@ssW0rdp@ssW0rdp@ssW0rd"},
+		{"f", "", "p@ssW0rddr0Wss@p"},
+		{"{", "", "@ssW0rdp"},
+		{"}", "", "dp@ssW0r"},
+		{"$1$2", "", "p@ssW0rd12"},
+		{"^2^1", "", "12p@ssW0rd"},
+		{"[", "", "@ssW0rd"},
+		{"]", "", "p@ssW0r"},
+		{"D3", "", "p@sW0rd"},
+		{"x04", "", "p@ss"},
+		{"O12", "", "psW0rd"},
+		{"i4!", "", "p@ss!W0rd"},
+		{"o3$", "", "p@s$W0rd"},
+		{"'6", "", "p@ssW0"},
+		{"ss$", "", "p@$$W0rd"},
+		{"@s", "", "p@W0rd"},
+		{"z2", "", "ppp@ssW0rd"},
+		{"Z2", "", "p@ssW0rddd"},
+		{"q", "", "pp@@ssssWW00rrdd"},
+		{"k", "", "@pssW0rd"},
+		{"K", "", "p@ssW0dr"},
+		{"*34", "", "p@sWs0rd"},
+		{"L2", "", "p@\xe6sW0rd"},
+		{"R2", "", "p@9sW0rd"},
+		{"+2", "", "p@tsW0rd"},
+		{"-1", "", "p?ssW0rd"},
+		{".1", "", "psssW0rd"},
+		{",1", "", "ppssW0rd"},
+		{"y2", "", "p@p@ssW0rd"},
+		{"Y2", "", "p@ssW0rdrd"},
+		{"E", "p@ssW0rd w0rld", "P@ssw0rd W0rld"},
+		{"e-", "p@ssW0rd-w0rld", "P@ssw0rd-W0rld"},
+
+		// Positions past the word leave it as it is.
+		{"TZ", "", "p@ssW0rd"},
+		{"D8", "", "p@ssW0rd"},
+		{"x59", "", "p@ssW0rd"},
+		{"i9!", "", "p@ssW0rd"},
+		{"i8!", "", "p@ssW0rd!"},
+		{"*38", "", "p@ssW0rd"},
+		{"y9", "", "p@ssW0rd"},
+		{"'A", "", "p@ssW0rd"},
+
+		// Chains, spaces ignored.
+		{"c $1 $!", "", "P@ssw0rd1!"},
+		{"sa@ so0", "password", "p@ssw0rd"},
+	} {
+		word := tc.word
+		if word == "" {
+			word = "p@ssW0rd"
+		}
+		r, err := ParseRule(tc.rule)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tc.rule, err)
+			continue
+		}
+		if got, ok := r.Apply(word); !ok || got != tc.want {
+			t.Errorf("%q on %q = %q, %t, want %q", tc.rule, word, got, ok, tc.want)
+		}
+	}
+}
+
+func TestRejections(t *testing.T) {
+	for _, tc := range []struct {
+		rule string
+		keep bool
+	}{
+		{"<8", true},
+		{"<7", false},
+		{">8", true},
+		{">9", false},
+		{"_8", true},
+		{"_7", false},
+		{"!z", true},
+		{"!@", false},
+		{"/@", true},
+		{"/z", false},
+		{"(p", true},
+		{"(@", false},
+		{")d", true},
+		{")p", false},
+		{"=1@", true},
+		{"=0@", false},
+		{"=9p", false},
+		{"%2s", true},
+		{"%3s", false},
+		// Rejections see the word as the functions before them left it.
+		{"$1 <8", false},
+		{"] )r", true},
+	} {
+		r, err := ParseRule(tc.rule)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tc.rule, err)
+			continue
+		}
+		if _, ok := r.Apply("p@ssW0rd"); ok != tc.keep {
+			t.Errorf("%q keeps p@ssW0rd: %t, want %t", tc.rule, ok, tc.keep)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, src := range []string{"a", "$", "T", "Ta", "x1", "*1a", "=a1", "i"} {
+		if _, err := ParseRule(src); !errors.Is(err, ErrRuleSyntax) {
+			t.Errorf("ParseRule(%q) = %v, want %v", src, err, ErrRuleSyntax)
+		}
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	rules, err := ReadRules("test.rule", strings.NewReader("# comment\n\nc\r\n$1 $2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Source != "c" || rules[1].Source != "$1 $2" {
+		t.Fatalf("ReadRules = %v", rules)
+	}
+	if _, err := ReadRules("bad.rule", strings.NewReader("c\n\nh\n")); err == nil || !strings.Contains(err.Error(), "bad.rule:3") {
+		t.Fatalf("ReadRules = %v, want an error at bad.rule:3", err)
+	}
+
+	combined := Combine(MustRules([]string{":", "c"}), MustRules([]string{":", "$1"}))
+	var got []string
+	for _, r := range combined {
+		s, _ := r.Apply("pass")
+		got = append(got, s)
+	}
+	if strings.Join(got, ",") != "pass,pass1,Pass,Pass1" {
+		t.Fatalf("Combine makes %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seithhq/crypto-finder/generator"
+)
+
+// Ruled is the indexable keyspace of every candidate of a base keyspace
+// under every rule, the rules changing fastest. Candidates are made on
+// demand, so a short list of remembered words can carry millions of rules.
+type Ruled struct {
+	base  generator.Keyspace
+	rules []Rule
+	size  uint64
+}
+
+func ApplyRules(base generator.Keyspace, rules []Rule) (*Ruled, error) {
+	n := uint64(len(rules))
+	if n == 0 {
+		return nil, fmt.Errorf("%w: no rules", ErrRuleSyntax)
+	}
+	if base.Size() > ^uint64(0)/n {
+		return nil, ErrTooLarge
+	}
+	return &Ruled{base: base, rules: rules, size: base.Size() * n}, nil
+}
+
+// Size counts every candidate under every rule, including the ones Each
+// skips as rejected or repeated.
+func (r *Ruled) Size() uint64 {
+	return r.size
+}
+
+// At returns the candidate at i, and false when Each skips it: its rule
+// rejects the word, or an earlier rule made the same of it.
+func (r *Ruled) At(i uint64) (string, bool) {
+	n := uint64(len(r.rules))
+	word, ok := r.base.At(i / n)
+	if !ok {
+		return "", false
+	}
+	candidate, ok := r.rules[i%n].Apply(word)
+	if !ok {
+		return "", false
+	}
+	for _, rule := range r.rules[:i%n] {
+		if earlier, ok := rule.Apply(word); ok && earlier == candidate {
+			return candidate, false
+		}
+	}
+	return candidate, true
+}
+
+// Each calls fn with the candidates at [from, to), skipping the ones a
+// rule rejects and the ones an earlier rule already made of the same word.
+func (r *Ruled) Each(from, to uint64, fn func(i uint64, candidate string) bool) {
+	to = min(to, r.size)
+	if from >= to {
+		return
+	}
+
+	n := uint64(len(r.rules))
+	seen := make(map[string]bool)
+	r.base.Each(from/n, (to+n-1)/n, func(w uint64, word string) bool {
+		clear(seen)
+		for k, rule := range r.rules {
+			i := w*n + uint64(k)
+			if i >= to {
+				return false
+			}
+			candidate, ok := rule.Apply(word)
+			if !ok || seen[candidate] {
+				continue
+			}
+			seen[candidate] = true
+			if i >= from && !fn(i, candidate) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Builtin are rule sets for the usual ways a remembered password is off.
+// Every set starts with the rule leaving a word as it is, so combining
+// sets also tries every set on its own.
+var Builtin = map[string][]string{
+	// The word as is, in lower case, capitalized or in upper case, each
+	// with nothing, 1, 12, 123, ! or 1! appended.
+	"common": product([]string{":", "l", "c", "u"}, []string{"", "$1", "$1$2", "$1$2$3", "$!", "$1$!"}),
+	"case":   {":", "l", "u", "c", "C", "t", "E"},
+	"leet": {":", "sa@", "sa4", "se3", "si1", "si!", "so0", "ss$", "ss5", "st7",
+		"sa@se3", "sa@so0", "se3so0", "si1so0", "sa@se3so0", "sa@se3si1so0", "sa4se3si1so0ss5st7"},
+	"digits": append(append([]string{":"}, product([]string{"$"}, chars("0123456789"))...),
+		product(product([]string{"$"}, chars("0123456789")), product([]string{"$"}, chars("0123456789")))...),
+	"prefix-digits": append([]string{":"}, product([]string{"^"}, chars("0123456789"))...),
+	"years":         append([]string{":"}, years(1950, 2030)...),
+	"reverse":       {":", "r", "d", "f", "{", "}", "k", "K"},
+}
+
+// Common is the "common" set, what -mutate tries.
+var Common = MustRules(Builtin["common"])
+
+// MustRules compiles rules known to be valid.
+func MustRules(sources []string) []Rule {
+	rules := make([]Rule, len(sources))
+	for i, src := range sources {
+		rule, err := ParseRule(src)
+		if err != nil {
+			panic(err)
+		}
+		rules[i] = rule
+	}
+	return rules
+}
+
+func product(a, b []string) []string {
+	var out []string
+	for _, x := range a {
+		for _, y := range b {
+			out = append(out, x+y)
+		}
+	}
+	return out
+}
+
+func chars(s string) []string {
+	return strings.Split(s, "")
+}
+
+// years appends every year of [from, to), both in full and as two digits.
+func years(from, to int) []string {
+	var full, short []string
+	for y := from; y < to; y++ {
+		s := fmt.Sprint(y)
+		full = append(full, "$"+strings.Join(chars(s), "$"))
+		short = append(short, "$"+strings.Join(chars(s[2:]), "$"))
+	}
+	return append(full, short...)
+}
@@ -0,0 +1,78 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seithhq/crypto-finder/generator"
+)
+
+// eachAgreesWithAt checks the generator.Keyspace contract: Each yields
+// exactly the indexes At reports, with the same candidates.
+func eachAgreesWithAt(t *testing.T, ks generator.Keyspace, from, to uint64) {
+	t.Helper()
+	next := from
+	skip := func(upto uint64) {
+		for ; next < upto; next++ {
+			if c, ok := ks.At(next); ok {
+				t.Fatalf("Each skips %d, but At gives %s", next, c)
+			}
+		}
+	}
+	ks.Each(from, to, func(i uint64, key string) bool {
+		skip(i)
+		if c, ok := ks.At(i); !ok || c != key {
+			t.Fatalf("Each gives %s at %d, At %s, %t", key, i, c, ok)
+		}
+		next = i + 1
+		return true
+	})
+	skip(min(to, ks.Size()))
+}
+
+func TestRuledAt(t *testing.T) {
+	words, err := ReadWordlist("test", strings.NewReader("hunter\nHunter2\nx\nhunter\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// l and : agree on lowercase words, c and u on x, and >2 rejects it.
+	rules := MustRules([]string{":", "l", "c", "u", ">2 $!", "sh@"})
+	r, err := ApplyRules(words, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size() != 24 {
+		t.Fatalf("Size = %d, want 24", r.Size())
+	}
+	for from := uint64(0); from < r.Size(); from++ {
+		eachAgreesWithAt(t, r, from, r.Size())
+	}
+	eachAgreesWithAt(t, r, 3, 9)
+
+	var got []string
+	r.Each(0, r.Size(), func(_ uint64, c string) bool {
+		got = append(got, c)
+		return true
+	})
+	want := "hunter Hunter HUNTER hunter! @unter " +
+		"Hunter2 hunter2 HUNTER2 Hunter2! " +
+		"x X " +
+		"hunter Hunter HUNTER hunter! @unter"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("Each yields %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestApplyRulesTooLarge(t *testing.T) {
+	m, err := ParseMask("?a?a?a?a?a?a?a?a?a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := MustRules(Builtin["digits"])
+	for len(rules) < 1<<10 {
+		rules = append(rules, rules...)
+	}
+	if _, err := ApplyRules(m, rules); err != ErrTooLarge {
+		t.Fatalf("ApplyRules = %v, want %v", err, ErrTooLarge)
+	}
+}
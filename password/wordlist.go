@@ -0,0 +1,48 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Wordlist is an indexable keyspace of the words of a list, in order.
+type Wordlist struct {
+	Source string
+	words  []string
+}
+
+// ReadWordlist reads one word per line from r. Words are taken as they
+// are, surrounding spaces included; only line endings are stripped.
+func ReadWordlist(source string, r io.Reader) (*Wordlist, error) {
+	w := &Wordlist{Source: source}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1<<16), 1<<20)
+	for scanner.Scan() {
+		w.words = append(w.words, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("wordlist %s: %w", source, err)
+	}
+	if len(w.words) == 0 {
+		return nil, fmt.Errorf("wordlist %s: no words", source)
+	}
+	return w, nil
+}
+
+func (w *Wordlist) Size() uint64 {
+	return uint64(len(w.words))
+}
+
+func (w *Wordlist) At(i uint64) (string, bool) {
+	return w.words[i], true
+}
+
+func (w *Wordlist) Each(from, to uint64, fn func(i uint64, candidate string) bool) {
+	for i := from; i < to && i < uint64(len(w.words)); i++ {
+		if !fn(i, w.words[i]) {
+			return
+		}
+	}
+}
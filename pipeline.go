@@ -0,0 +1,176 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/seithhq/crypto-finder/key"
+)
+
+// batchSize is how many candidates move through the pipeline per channel
+// operation.
+const batchSize = 256
+
+// textBatchSize is the batch size of texts. Unlocking one runs a KDF,
+// from milliseconds for a mnemonic to seconds for a scrypt keystore, so
+// text batches are kept small enough to spread over every worker and to
+// finish soon after an interrupt.
+const textBatchSize = 8
+
+// batch is the run of candidate indexes [index, to). Hex candidates come
+// decoded in keys, with the ones that failed to decode in errs, while
+// mnemonics and passwords come as texts for the worker to unlock. A walk
+// batch carries no keys: the worker derives the mask range itself.
+type batch struct {
+	index, to uint64
+	walk      bool
+	keys      []key.Private
+	texts     []string
+	// at holds the index of every text.
+	at   []uint64
+	errs []error
+}
+
+var keyBuffers = sync.Pool{
+	New: func() any {
+		keys := make([]key.Private, 0, batchSize)
+		return &keys
+	},
+}
+
+// runPool feeds jobs to workers goroutines through channels bounded by the
+// worker count, so a slow consumer stalls feed instead of growing memory.
+// newWorker is called once per goroutine to set up its private state. The
+// returned channel is closed after the last result.
+func runPool[B, R any](workers int, feed func(send func(B) bool), newWorker func() func(B) R) <-chan R {
+	jobs := make(chan B, 2*workers)
+	results := make(chan R, 2*workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work := newWorker()
+			for j := range jobs {
+				results <- work(j)
+			}
+		}()
+	}
+
+	go func() {
+		feed(func(j B) bool {
+			jobs <- j
+			return true
+		})
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// batcher groups candidates into batches. Hex keys are decoded on the
+// way; with texts set, candidates are passed on for the workers to unlock.
+// With sparse set, indexes between two candidates were skipped by the
+// keyspace and go into the batch of the next one, so they are checkpointed
+// as tested; otherwise a gap starts a new batch. With lines set, the
+// candidates are lines of input, and errors name their line.
+type batcher struct {
+	send   func(batch) bool
+	texts  bool
+	sparse bool
+	lines  bool
+	b      batch
+	open   bool
+	// next is where the following batch starts in sparse mode.
+	next uint64
+}
+
+func (bt *batcher) start(i uint64) {
+	if bt.sparse {
+		i = bt.next
+	}
+	bt.b = batch{index: i, to: i}
+	if !bt.texts {
+		bt.b.keys = (*keyBuffers.Get().(*[]key.Private))[:0]
+	}
+	bt.open = true
+}
+
+// add takes the candidate at index i, flushing the current batch first
+// when it is full or i does not follow it.
+func (bt *batcher) add(i uint64, candidate string) bool {
+	if !bt.open {
+		bt.start(i)
+	}
+	limit := batchSize
+	if bt.texts {
+		limit = textBatchSize
+	}
+	if n := len(bt.b.keys) + len(bt.b.texts) + len(bt.b.errs); n == limit || (i != bt.b.to && !bt.sparse) {
+		if !bt.flush() {
+			return false
+		}
+		return bt.add(i, candidate)
+	}
+
+	if bt.texts {
+		bt.b.texts = append(bt.b.texts, candidate)
+		bt.b.at = append(bt.b.at, i)
+	} else if prv, err := key.Parse(candidate); err != nil {
+		bt.b.errs = append(bt.b.errs, bt.reject(i, candidate, err))
+	} else {
+		bt.b.keys = append(bt.b.keys, prv)
+	}
+	bt.b.to = i + 1
+
+	return true
+}
+
+// reject ties a candidate that is no key to where it came from.
+func (bt *batcher) reject(i uint64, candidate string, err error) error {
+	if bt.lines {
+		return &key.LineError{Line: int(i) + 1, Input: candidate, Err: err}
+	}
+	return &key.IndexError{Index: i, Input: candidate, Err: err}
+}
+
+// seek flushes the current batch and starts the next one at index i, for
+// candidates that do not continue it, like the next pending range.
+func (bt *batcher) seek(i uint64) bool {
+	ok := bt.flush()
+	bt.next = i
+	return ok
+}
+
+// cover extends the current batch up to index to and flushes it, so that
+// indexes skipped at the end of a range are checkpointed as tested too.
+func (bt *batcher) cover(to uint64) bool {
+	if !bt.open {
+		if bt.next >= to {
+			return true
+		}
+		bt.start(bt.next)
+	}
+	bt.b.to = to
+	return bt.flush()
+}
+
+func (bt *batcher) flush() bool {
+	if !bt.open {
+		return true
+	}
+	b := bt.b
+	bt.b, bt.open, bt.next = batch{}, false, b.to
+	if b.to == b.index {
+		if b.keys != nil {
+			keyBuffers.Put(&b.keys)
+		}
+		return true
+	}
+	return bt.send(b)
+}
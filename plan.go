@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/seithhq/crypto-finder/address"
+	"github.com/seithhq/crypto-finder/checkpoint"
+	"github.com/seithhq/crypto-finder/generator"
+	"github.com/seithhq/crypto-finder/walker"
+)
+
+// plan reports what a search with the same candidate flags would cost
+// without running it: the exact candidate count, the duplicates the
+// keyspace skips, the runtime at the rate measured on this machine and the
+// size of its checkpoint file. A run over -budget is warned about, or
+// refused with a non-zero exit status under -refuse.
+func plan(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	var candidateOpts candidateFlags
+	var targetAddresses addressList
+	candidateOpts.register(fs)
+	start := fs.Uint64("start", 0, "index of the first candidate to test")
+	count := fs.Uint64("count", 0, "number of candidates to test, 0 for all")
+	backendName := fs.String("backend", "auto", backendUsage)
+	workers := fs.Int("workers", runtime.NumCPU(), "number of derivation workers")
+	sample := fs.Uint64("sample", 1<<14, "candidates to time the pipeline on")
+	rate := fs.Float64("rate", 0, "keys/s to plan with instead of measuring them, e.g. from bench on the machine that will run the search")
+	budget := fs.Duration("budget", 0, "wall-clock budget for the search, 0 for none")
+	refuse := fs.Bool("refuse", false, "exit with status 1 instead of warning when the search exceeds -budget")
+	fs.Var(&targetAddresses, "target", targetUsage)
+	fs.Parse(args)
+
+	ks, size, spec, err := benchKeyspace(&candidateOpts, *sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	end := max(size, *start)
+	if *count > 0 {
+		end = min(end, *start+*count)
+	}
+	total := end - *start
+
+	fmt.Printf("%-20s %s\n", "spec", spec)
+	fmt.Printf("%-20s %d\n", "candidates", total)
+	if d, ok := ks.(generator.Deduplicated); ok {
+		n, counted := d.Duplicates()
+		_, skipped := ks.(generator.Layered)
+		switch {
+		case !counted:
+			fmt.Printf("%-20s too many overlapping layouts to count\n", "duplicates removed")
+		case skipped && total == size:
+			// Mask indexes still cover the duplicates it skips.
+			fmt.Printf("%-20s %d, %d unique candidates\n", "duplicates removed", n, total-n)
+		case skipped:
+			fmt.Printf("%-20s %d in the whole keyspace\n", "duplicates removed", n)
+		default:
+			fmt.Printf("%-20s %d\n", "duplicates removed", n)
+		}
+	}
+
+	be, err := pickBackend(*backendName, ks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	targets, err := candidateOpts.targets(targetAddresses)
+	if err != nil {
+		log.Fatal(err)
+	}
+	match, err := newMatcher(targets, func() {})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	unlock, err := candidateOpts.unlocker()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	measured := "given"
+	if *rate <= 0 && ks.Size() > 0 {
+		var walk *walker.Walker
+		if m, ok := ks.(generator.Layered); ok {
+			walk = walker.New(m)
+		}
+		n, elapsed := endToEnd(ks, walk, unlock, be, max(*workers, 1), match, min(*sample, ks.Size()))
+		*rate = float64(n) / elapsed.Seconds()
+		name := "incremental walk"
+		if walk == nil {
+			name = be.Name()
+		}
+		measured = fmt.Sprintf("measured on %d candidates, %s, %d workers", n, name, max(*workers, 1))
+	}
+	duration := time.Duration(0)
+	if *rate > 0 {
+		fmt.Printf("%-20s %.0f keys/s, %s\n", "rate", *rate, measured)
+		fmt.Printf("%-20s %s\n", "runtime", eta(total, *rate))
+		duration = time.Duration(min(float64(total) / *rate * float64(time.Second), math.MaxInt64))
+	}
+
+	stateSize, err := checkpointSize(spec, *start, end, max(*workers, 1), match, total)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-20s ~%d bytes\n", "checkpoint", stateSize)
+
+	if *budget > 0 && duration > *budget {
+		msg := fmt.Sprintf("runtime exceeds the %s budget", *budget)
+		if *refuse {
+			fmt.Fprintln(os.Stderr, "refusing: "+msg)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "warning: "+msg)
+	}
+}
+
+// checkpointSize estimates the largest state file a search of [start, end)
+// writes. Workers finish batches out of order, so up to one range per
+// batch in flight stays unmerged. Hits are every exact target plus the
+// false positives expected from the patterns; a search without targets
+// keeps no hits in its state.
+func checkpointSize(spec string, start, end uint64, workers int, match *matcher, total uint64) (int, error) {
+	state := checkpoint.New(spec, start, end)
+
+	inFlight := uint64(5*workers + 1)
+	for i := uint64(0); i < inFlight; i++ {
+		from := end - min(end-start, 2*(inFlight-i)*batchSize)
+		state.Complete(from, from+1)
+	}
+
+	empty, err := state.Marshal()
+	if err != nil || match.all {
+		return len(empty), err
+	}
+
+	hits := float64(match.targets.Len())
+	for _, pattern := range match.patterns {
+		hits += pattern.FalsePositives(total)
+	}
+	hit := checkpoint.Hit{
+		Private: strings.Repeat("0", generator.KeyLength),
+		Address: strings.Repeat("0", 2*address.Length),
+	}
+	if len(match.patterns) > 0 {
+		hit.Note = fmt.Sprintf("%s;bits=%.1f;false_positives=%.3g", match.patterns[0].Source, 0.0, 0.0)
+	}
+	state.AddHit(hit)
+	one, err := state.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(empty) + int(math.Ceil(hits))*(len(one)-len(empty)), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/seithhq/crypto-finder/checkpoint"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+// decryptPresale decrypts a presale wallet with a known password and
+// prints its key, checked against the ethaddr of the wallet. Without
+// -password, the password is read from stdin.
+func decryptPresale(args []string) {
+	fs := flag.NewFlagSet("presale", flag.ExitOnError)
+	var opts candidateFlags
+	fs.StringVar(&opts.presale, "presale", "", "presale wallet: JSON with encseed and ethaddr")
+	pass := fs.String("password", "", "wallet password, read from stdin when empty")
+	fs.Parse(args)
+
+	if opts.presale == "" && fs.NArg() == 1 {
+		opts.presale = fs.Arg(0)
+	}
+	if opts.presale == "" {
+		log.Fatal("presale needs -presale")
+	}
+	wallet, err := opts.openPresale()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	seed, err := wallet.Decrypt(readPassword(*pass))
+	if err != nil {
+		log.Fatal(err)
+	}
+	prv, err := key.FromBytes(Keccak256(seed))
+	if err != nil {
+		log.Fatal(err)
+	}
+	addr, err := addressFromPrivate(prv.Hex())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if addr != wallet.Address {
+		log.Fatalf("wrong password: the key decrypted is of 0x%s, not of ethaddr 0x%s", addr, wallet.Address)
+	}
+
+	fmt.Println("private_key;address")
+	printHit(os.Stdout, checkpoint.Hit{Private: prv.Hex(), Address: addr})
+}
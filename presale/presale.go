@@ -0,0 +1,89 @@
+package presale
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	// ErrPassword is returned by Decrypt when the padding of the decrypted
+	// seed is wrong. A wrong password passes that check one time in about
+	// 256, so only the address of the key tells it for sure.
+	ErrPassword = errors.New("wrong password")
+	ErrFormat   = errors.New("not a presale wallet")
+)
+
+// Wallet is an Ethereum presale wallet of 2014: the seed is encrypted with
+// AES-128-CBC under PBKDF2-SHA256(password, password, 2000 rounds), and the
+// private key is the Keccak-256 of the seed.
+type Wallet struct {
+	// Address is the lowercase hex ethaddr of the wallet.
+	Address string
+
+	iv, ciphertext []byte
+}
+
+type walletJSON struct {
+	EncSeed string `json:"encseed"`
+	EthAddr string `json:"ethaddr"`
+}
+
+// Load reads a presale wallet, JSON with encseed and ethaddr among other
+// fields.
+func Load(r io.Reader) (*Wallet, error) {
+	var j walletJSON
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	if j.EncSeed == "" || j.EthAddr == "" {
+		return nil, fmt.Errorf("%w: want encseed and ethaddr", ErrFormat)
+	}
+
+	encseed, err := hex.DecodeString(j.EncSeed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: encseed: %w", ErrFormat, err)
+	}
+	// encseed is the IV followed by whole blocks of ciphertext.
+	if len(encseed) < 2*aes.BlockSize || len(encseed)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: encseed has %d bytes", ErrFormat, len(encseed))
+	}
+
+	return &Wallet{
+		Address:    strings.ToLower(strings.TrimPrefix(j.EthAddr, "0x")),
+		iv:         encseed[:aes.BlockSize],
+		ciphertext: encseed[aes.BlockSize:],
+	}, nil
+}
+
+// Decrypt returns the seed the password decrypts, if its PKCS#7 padding
+// is right.
+func (w *Wallet) Decrypt(password string) ([]byte, error) {
+	pass := []byte(password)
+	block, err := aes.NewCipher(pbkdf2.Key(pass, pass, 2000, 16, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, len(w.ciphertext))
+	cipher.NewCBCDecrypter(block, w.iv).CryptBlocks(plain, w.ciphertext)
+
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, ErrPassword
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, ErrPassword
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
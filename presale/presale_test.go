@@ -0,0 +1,73 @@
+package presale
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testdata/guswallet.json is go-ethereum's cmd/geth/testdata/guswallet.json,
+// whose password is "foo".
+func TestDecrypt(t *testing.T) {
+	file, err := os.Open("testdata/guswallet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	w, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "d4584b5f6229b7be90727b0fc8c6b91bb427821f"; w.Address != want {
+		t.Fatalf("Address = %s, want %s", w.Address, want)
+	}
+
+	seed, err := w.Decrypt("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := address(t, seed); got != w.Address {
+		t.Fatalf("key of the seed is of %s, want %s", got, w.Address)
+	}
+
+	// Padding lets a wrong password through now and then; the address
+	// never matches.
+	for _, password := range []string{"", "Foo", "foo ", "bar"} {
+		seed, err := w.Decrypt(password)
+		if err == nil && address(t, seed) == w.Address {
+			t.Errorf("Decrypt(%q) unlocked the wallet", password)
+		}
+		if err != nil && !errors.Is(err, ErrPassword) {
+			t.Errorf("Decrypt(%q) = %v, want %v", password, err, ErrPassword)
+		}
+	}
+}
+
+func address(t *testing.T, seed []byte) string {
+	prv, err := crypto.ToECDSA(crypto.Keccak256(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(crypto.PubkeyToAddress(prv.PublicKey).Bytes())
+}
+
+func TestLoadRejects(t *testing.T) {
+	const addr = `"ethaddr": "d4584b5f6229b7be90727b0fc8c6b91bb427821f"`
+	for _, tc := range []struct{ name, json string }{
+		{"not json", `encseed`},
+		{"no ethaddr", `{"encseed": "` + strings.Repeat("00", 32) + `"}`},
+		{"no encseed", `{` + addr + `}`},
+		{"hex", `{"encseed": "zz", ` + addr + `}`},
+		{"iv only", `{"encseed": "` + strings.Repeat("00", 16) + `", ` + addr + `}`},
+		{"partial block", `{"encseed": "` + strings.Repeat("00", 40) + `", ` + addr + `}`},
+	} {
+		if _, err := Load(strings.NewReader(tc.json)); !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: Load = %v, want %v", tc.name, err, ErrFormat)
+		}
+	}
+}
@@ -0,0 +1,820 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/seithhq/crypto-finder/address"
+	"github.com/seithhq/crypto-finder/backend"
+	"github.com/seithhq/crypto-finder/checkpoint"
+	"github.com/seithhq/crypto-finder/generator"
+	"github.com/seithhq/crypto-finder/hdwallet"
+	"github.com/seithhq/crypto-finder/key"
+	"github.com/seithhq/crypto-finder/keystore"
+	"github.com/seithhq/crypto-finder/metamask"
+	"github.com/seithhq/crypto-finder/mnemonic"
+	"github.com/seithhq/crypto-finder/password"
+	"github.com/seithhq/crypto-finder/presale"
+	"github.com/seithhq/crypto-finder/source"
+	"github.com/seithhq/crypto-finder/target"
+	"github.com/seithhq/crypto-finder/walker"
+)
+
+// candidateFlags are the keyspace options shared by the search, bench and
+// plan commands.
+type candidateFlags struct {
+	known, mask, typo, input string
+	missing, edits           int
+	confidence               generator.Confidence
+	phrase, passphrase, path string
+	accounts, indexes        string
+	wordlist, passwordMask   string
+	rules                    string
+	mutate                   bool
+	keystore, vault, presale string
+	wallet                   *keystore.Keystore
+	metamaskVault            *metamask.Vault
+	presaleWallet            *presale.Wallet
+}
+
+func (f *candidateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.known, "known", "", "known hex fragment of the private key")
+	fs.IntVar(&f.missing, "missing", 0, "number of hex characters missing around the known fragment")
+	fs.StringVar(&f.mask, "mask", "", "candidate mask, e.g. 0a5c??ffb9[0-7]*")
+	fs.StringVar(&f.typo, "typo", "", "complete but possibly mistyped private key")
+	fs.IntVar(&f.edits, "edits", 1, fmt.Sprintf("maximum number of transcription errors in -typo, at most %d", generator.MaxEdits))
+	fs.Var(preferenceList{&f.confidence}, "prefer", "likely characters of a key position counted from 1, position=chars[@chance], e.g. 12=b6@0.8, repeatable; ranks -mask and -known candidates")
+	fs.StringVar(&f.confidence.MissingAt, "missing-at", "", "end of the key more likely missing characters: start or end; ranks -mask and -known candidates")
+	fs.StringVar(&f.phrase, "mnemonic", "", "remembered BIP39 phrase: ? for an unknown word, word~ for a suspected typo")
+	fs.StringVar(&f.passphrase, "passphrase", "", "BIP39 passphrase of -mnemonic")
+	fs.StringVar(&f.path, "path", "m/44'/60'/0'/0/0", "BIP32 derivation paths of -mnemonic keys: comma-separated templates like m/44'/60'/{a}'/0/{i}, presets metamask, bip44, ledger-live, mew, or all")
+	fs.StringVar(&f.accounts, "accounts", "0-4", "accounts {a} of -path templates, n or from-to")
+	fs.StringVar(&f.indexes, "indexes", "0-9", "address indexes {i} of -path templates, n or from-to")
+	fs.StringVar(&f.keystore, "keystore", "", "version 3 keystore file whose password to search, e.g. UTC--...json")
+	fs.StringVar(&f.presale, "presale", "", "2014 Ethereum presale wallet whose password to search: JSON with encseed and ethaddr")
+	fs.StringVar(&f.vault, "vault", "", "MetaMask vault whose password to search: JSON with data, iv and salt")
+	fs.StringVar(&f.wordlist, "wordlist", "", "password candidates, one per line: file, - for stdin, optionally gzip or zstd compressed and encrypted; searches the -keystore, -vault or -presale password or the -mnemonic passphrase")
+	fs.StringVar(&f.passwordMask, "password-mask", "", "password candidates as a hashcat style mask, e.g. ?u?l?l?l?d?d; searches the -keystore, -vault or -presale password or the -mnemonic passphrase")
+	fs.StringVar(&f.rules, "rules", "", "hashcat rule files or built-in sets (common, case, leet, digits, prefix-digits, years, reverse) applied to every -wordlist or -password-mask candidate; comma-separated sets are chained like repeated hashcat -r")
+	fs.BoolVar(&f.mutate, "mutate", false, "also try the common case and suffix variants of every password candidate, like -rules common")
+	fs.StringVar(&f.input, "input", "", "candidate list, one hex key per line: file, named pipe, - for stdin, optionally gzip or zstd compressed and encrypted")
+}
+
+// open returns the candidates either as an indexable keyspace or, for
+// -input, as a stream, together with a spec identifying them in
+// checkpoints.
+func (f *candidateFlags) open() (generator.Keyspace, io.ReadCloser, string, error) {
+	if err := f.check(); err != nil {
+		return nil, nil, "", err
+	}
+
+	switch {
+	case f.keystore != "":
+		if !f.searchesPasswords() {
+			return nil, nil, "", errors.New("-keystore needs -wordlist or -password-mask")
+		}
+		ks, spec, err := f.passwords()
+		return ks, nil, fmt.Sprintf("keystore=%s;%s", f.keystore, spec), err
+	case f.vault != "":
+		if !f.searchesPasswords() {
+			return nil, nil, "", errors.New("-vault needs -wordlist or -password-mask")
+		}
+		ks, spec, err := f.passwords()
+		return ks, nil, fmt.Sprintf("vault=%s;%s", f.vault, spec), err
+	case f.presale != "":
+		if !f.searchesPasswords() {
+			return nil, nil, "", errors.New("-presale needs -wordlist or -password-mask")
+		}
+		ks, spec, err := f.passwords()
+		return ks, nil, fmt.Sprintf("presale=%s;%s", f.presale, spec), err
+	case f.typo != "":
+		ks, err := generator.NewTypos(f.typo, f.edits)
+		return ks, nil, fmt.Sprintf("typo=%s;edits=%d", f.typo, f.edits), err
+	case f.mask != "":
+		m, err := generator.ParseMask(f.mask)
+		return f.rank(m, fmt.Sprintf("mask=%s", f.mask), err)
+	case f.known != "":
+		m, err := generator.NewSplit(f.known, f.missing)
+		return f.rank(m, fmt.Sprintf("known=%s;missing=%d", f.known, f.missing), err)
+	case f.phrase != "" && f.searchesPasswords():
+		ks, spec, err := f.passwords()
+		return ks, nil, fmt.Sprintf("mnemonic=%s;%s;%s", f.phrase, spec, f.pathSpec()), err
+	case f.phrase != "":
+		ks, err := mnemonic.ParsePhrase(f.phrase)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return ks, nil, fmt.Sprintf("mnemonic=%s;passphrase=%s;%s", f.phrase, f.passphrase, f.pathSpec()), nil
+	case f.input != "":
+		input, err := source.Open(f.input)
+		return nil, input, fmt.Sprintf("input=%s", f.input), err
+	}
+
+	if f.searchesPasswords() {
+		return nil, nil, "", errors.New("-wordlist and -password-mask need -keystore, -vault, -presale or -mnemonic")
+	}
+	return nil, nil, "", errors.New("no candidates: pass -known, -mask, -typo, -mnemonic, -keystore, -vault, -presale or -input")
+}
+
+// check rejects flags that open would otherwise ignore: a second source of
+// candidates, or options the chosen one does not use.
+func (f *candidateFlags) check() error {
+	var modes []string
+	for _, m := range []struct{ flag, value string }{
+		{"-known", f.known}, {"-mask", f.mask}, {"-typo", f.typo}, {"-mnemonic", f.phrase},
+		{"-keystore", f.keystore}, {"-vault", f.vault}, {"-presale", f.presale}, {"-input", f.input},
+	} {
+		if m.value != "" {
+			modes = append(modes, m.flag)
+		}
+	}
+	if len(modes) > 1 {
+		return fmt.Errorf("%s cannot be combined: search one at a time", strings.Join(modes, " and "))
+	}
+
+	switch {
+	case (f.confidence.Prefer != nil || f.confidence.MissingAt != "") && f.mask == "" && f.known == "":
+		return errors.New("-prefer and -missing-at only rank -mask and -known candidates")
+	case f.wordlist != "" && f.passwordMask != "":
+		return errors.New("-wordlist and -password-mask cannot be combined")
+	case (f.rules != "" || f.mutate) && !f.searchesPasswords():
+		return errors.New("-rules and -mutate need -wordlist or -password-mask")
+	case f.passphrase != "" && (f.phrase == "" || f.searchesPasswords()):
+		return errors.New("-passphrase needs -mnemonic, and is searched instead with -wordlist or -password-mask")
+	}
+	return nil
+}
+
+// searchesPasswords reports whether password candidates were given.
+func (f *candidateFlags) searchesPasswords() bool {
+	return f.wordlist != "" || f.passwordMask != ""
+}
+
+// passwords returns the password candidates of -wordlist or -password-mask
+// with their spec.
+func (f *candidateFlags) passwords() (generator.Keyspace, string, error) {
+	base, spec, err := f.basePasswords()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var rules []password.Rule
+	if f.mutate {
+		rules = password.Common
+		spec += ";mutate"
+	}
+	if f.rules != "" {
+		for _, name := range strings.Split(f.rules, ",") {
+			set, err := ruleSet(name)
+			if err != nil {
+				return nil, "", err
+			}
+			if rules == nil {
+				rules = set
+			} else {
+				rules = password.Combine(rules, set)
+			}
+		}
+		spec += ";rules=" + f.rules
+	}
+	if rules == nil {
+		return base, spec, nil
+	}
+
+	ruled, err := password.ApplyRules(base, rules)
+	return ruled, spec, err
+}
+
+// basePasswords returns the -password-mask or -wordlist candidates before
+// any rule.
+func (f *candidateFlags) basePasswords() (generator.Keyspace, string, error) {
+	if f.passwordMask != "" {
+		m, err := password.ParseMask(f.passwordMask)
+		return m, "password-mask=" + f.passwordMask, err
+	}
+
+	r, err := source.Open(f.wordlist)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+	w, err := password.ReadWordlist(f.wordlist, r)
+	return w, "wordlist=" + f.wordlist, err
+}
+
+// ruleSet returns a built-in rule set or reads a hashcat rule file.
+func ruleSet(name string) ([]password.Rule, error) {
+	if set, ok := password.Builtin[name]; ok {
+		return password.MustRules(set), nil
+	}
+	r, err := source.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("rules %q: neither a built-in set nor a rule file: %w", name, err)
+	}
+	defer r.Close()
+	return password.ReadRules(name, r)
+}
+
+// fixedPhrase returns -mnemonic when it is a single mnemonic with a valid
+// checksum, as a passphrase search needs.
+func (f *candidateFlags) fixedPhrase() (string, error) {
+	p, err := mnemonic.ParsePhrase(f.phrase)
+	if err != nil {
+		return "", err
+	}
+	if p.Size() != 1 {
+		return "", errors.New("passphrase search needs the complete mnemonic, without ? or ~")
+	}
+	phrase := ""
+	p.Each(0, 1, func(_ uint64, valid string) bool {
+		phrase = valid
+		return false
+	})
+	if phrase == "" {
+		return "", fmt.Errorf("mnemonic %q: invalid checksum", f.phrase)
+	}
+	return phrase, nil
+}
+
+// empty reports whether no candidates were given.
+func (f *candidateFlags) empty() bool {
+	return f.known == "" && f.mask == "" && f.typo == "" && f.phrase == "" && f.keystore == "" && f.vault == "" && f.presale == "" && f.input == ""
+}
+
+// decrypts reports whether the candidates are the passwords of an
+// encrypted file, which only the right one decrypts.
+func (f *candidateFlags) decrypts() bool {
+	return f.keystore != "" || f.vault != ""
+}
+
+// openKeystore loads -keystore once, or returns nil without one.
+func (f *candidateFlags) openKeystore() (*keystore.Keystore, error) {
+	if f.keystore == "" || f.wallet != nil {
+		return f.wallet, nil
+	}
+	file, err := source.Open(f.keystore)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if f.wallet, err = keystore.Load(file); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.keystore, err)
+	}
+	return f.wallet, nil
+}
+
+// openVault loads -vault once, or returns nil without one.
+func (f *candidateFlags) openVault() (*metamask.Vault, error) {
+	if f.vault == "" || f.metamaskVault != nil {
+		return f.metamaskVault, nil
+	}
+	file, err := source.Open(f.vault)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if f.metamaskVault, err = metamask.Load(file); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.vault, err)
+	}
+	return f.metamaskVault, nil
+}
+
+// openPresale loads -presale once, or returns nil without one.
+func (f *candidateFlags) openPresale() (*presale.Wallet, error) {
+	if f.presale == "" || f.presaleWallet != nil {
+		return f.presaleWallet, nil
+	}
+	file, err := source.Open(f.presale)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if f.presaleWallet, err = presale.Load(file); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.presale, err)
+	}
+	return f.presaleWallet, nil
+}
+
+// targets returns the given target addresses together with the address
+// of a -presale wallet. Without a MAC, a wrong presale password can pass
+// decryption, so only its address tells the right key.
+func (f *candidateFlags) targets(given []string) ([]string, error) {
+	wallet, err := f.openPresale()
+	if err != nil || wallet == nil {
+		return given, err
+	}
+	return append(given, "0x"+wallet.Address), nil
+}
+
+// unlocker returns the unlocker factory of text candidates, or nil when
+// the candidates are hex keys.
+func (f *candidateFlags) unlocker() (func() unlocker, error) {
+	if f.keystore != "" {
+		wallet, err := f.openKeystore()
+		if err != nil {
+			return nil, err
+		}
+		return func() unlocker {
+			return func(password string) ([]unlocked, error) {
+				prv, err := wallet.Unlock(password)
+				if errors.Is(err, keystore.ErrPassword) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return []unlocked{{key: prv}}, nil
+			}
+		}, nil
+	}
+	if f.vault != "" {
+		vault, err := f.openVault()
+		if err != nil {
+			return nil, err
+		}
+		return func() unlocker {
+			return func(password string) ([]unlocked, error) {
+				keyrings, err := vault.Unlock(password)
+				if errors.Is(err, metamask.ErrPassword) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return vaultKeys(keyrings)
+			}
+		}, nil
+	}
+	if f.presale != "" {
+		wallet, err := f.openPresale()
+		if err != nil {
+			return nil, err
+		}
+		return func() unlocker {
+			return func(password string) ([]unlocked, error) {
+				seed, err := wallet.Decrypt(password)
+				if errors.Is(err, presale.ErrPassword) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				prv, err := key.FromBytes(Keccak256(seed))
+				if err != nil {
+					return nil, err
+				}
+				return []unlocked{{key: prv}}, nil
+			}
+		}, nil
+	}
+	if f.phrase == "" {
+		return nil, nil
+	}
+
+	paths, err := f.paths()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.searchesPasswords() {
+		phrase, err := f.fixedPhrase()
+		if err != nil {
+			return nil, err
+		}
+		return func() unlocker {
+			return func(passphrase string) ([]unlocked, error) {
+				return deriveAll(mnemonic.Seed(phrase, passphrase), paths)
+			}
+		}, nil
+	}
+	return func() unlocker {
+		return func(phrase string) ([]unlocked, error) {
+			return deriveAll(mnemonic.Seed(phrase, f.passphrase), paths)
+		}
+	}, nil
+}
+
+// paths expands -path with the -accounts and -indexes ranges.
+func (f *candidateFlags) paths() ([]hdwallet.Path, error) {
+	accounts, err := hdwallet.ParseRange(f.accounts)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := hdwallet.ParseRange(f.indexes)
+	if err != nil {
+		return nil, err
+	}
+	return hdwallet.Expand(f.path, accounts, indexes)
+}
+
+// pathSpec identifies the derivation paths in checkpoints.
+func (f *candidateFlags) pathSpec() string {
+	if !strings.Contains(f.path, "{") && hdwallet.Presets[f.path] == "" && f.path != "all" {
+		return "path=" + f.path
+	}
+	return fmt.Sprintf("path=%s;accounts=%s;indexes=%s", f.path, f.accounts, f.indexes)
+}
+
+// deriveAll derives the key at every path of a BIP39 seed. Paths running
+// into an invalid child are skipped, as BIP32 wallets do.
+func deriveAll(seed []byte, paths []hdwallet.Path) ([]unlocked, error) {
+	master, err := hdwallet.Master(seed)
+	if err != nil {
+		return nil, err
+	}
+	keys, _ := master.DeriveAll(paths)
+	found := make([]unlocked, 0, len(keys))
+	for n, k := range keys {
+		if k != nil {
+			found = append(found, unlocked{key: k.Private, path: paths[n].String()})
+		}
+	}
+	return found, nil
+}
+
+// header returns the first output line, naming the fields of printHit.
+func (f *candidateFlags) header() string {
+	switch {
+	case f.keystore != "", f.presale != "":
+		return "private_key;address;password"
+	case f.vault != "":
+		return "private_key;address;path;password"
+	case f.phrase != "" && f.searchesPasswords():
+		return "private_key;address;path;passphrase"
+	case f.phrase != "":
+		return "private_key;address;path;mnemonic"
+	}
+	return "private_key;address"
+}
+
+// rank orders the candidates of m by the -prefer and -missing-at
+// confidence, if any was given.
+func (f *candidateFlags) rank(m *generator.Mask, spec string, err error) (generator.Keyspace, io.ReadCloser, string, error) {
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if f.confidence.Prefer == nil && f.confidence.MissingAt == "" {
+		return m, nil, spec, nil
+	}
+
+	ranked, err := m.Rank(f.confidence)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ranked, nil, spec + ";" + f.confidence.String(), nil
+}
+
+// preferenceList is the -prefer flag, collecting the preferences of
+// several positions.
+type preferenceList struct {
+	c *generator.Confidence
+}
+
+func (l preferenceList) String() string {
+	if l.c == nil || l.c.Prefer == nil {
+		return ""
+	}
+	return l.c.String()
+}
+
+func (l preferenceList) Set(value string) error {
+	p, pref, err := generator.ParsePreference(value)
+	if err != nil {
+		return err
+	}
+	if l.c.Prefer == nil {
+		l.c.Prefer = make(map[int]generator.Preference)
+	}
+	l.c.Prefer[p] = pref
+	return nil
+}
+
+// result is sent once per batch, after every hit of the batch, so that its
+// indexes can be checkpointed as tested. skipped counts the duplicate
+// candidates of the batch that were not derived.
+type result struct {
+	index, to uint64
+	hits      []checkpoint.Hit
+	errs      []error
+	skipped   uint64
+}
+
+// matcher decides which derived addresses are reported: all of them when
+// there are no targets, otherwise pattern and exact target hits.
+type matcher struct {
+	targets  *target.Set
+	exact    int
+	patterns []*target.Pattern
+	all      bool
+	tested   atomic.Uint64
+	// halt is called once every exact target has been found.
+	halt func()
+}
+
+func newMatcher(raw []string, halt func()) (*matcher, error) {
+	m := &matcher{all: len(raw) == 0, halt: halt}
+
+	var exact []string
+	for _, r := range raw {
+		if !target.IsPattern(r) {
+			exact = append(exact, r)
+			continue
+		}
+		pattern, err := target.ParsePattern(r)
+		if err != nil {
+			return nil, err
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+
+	targets, err := target.NewSet(exact)
+	if err != nil {
+		return nil, err
+	}
+	m.targets = targets
+
+	return m, nil
+}
+
+// check appends hit to r if addr is reported. hit holds what identifies
+// the candidate; check fills in the address and the match details.
+func (m *matcher) check(r *result, hit checkpoint.Hit, addr *[address.Length]byte) {
+	n := m.tested.Add(1)
+	if m.all {
+		hit.Address = hex.EncodeToString(addr[:])
+		r.hits = append(r.hits, hit)
+		return
+	}
+
+	if len(m.patterns) > 0 {
+		hexAddress := hex.EncodeToString(addr[:])
+		for _, pattern := range m.patterns {
+			if pattern.Match(hexAddress) {
+				h := hit
+				h.Address = hexAddress
+				h.Note = fmt.Sprintf("%s;bits=%.1f;false_positives=%.3g", pattern.Source, pattern.Bits, pattern.FalsePositives(n))
+				r.hits = append(r.hits, h)
+			}
+		}
+	}
+
+	if m.targets.Contains(*addr) {
+		hit.Address = hex.EncodeToString(addr[:])
+		r.hits = append(r.hits, hit)
+		if m.targets.Hit(*addr) {
+			m.halt()
+		}
+	}
+}
+
+// unlocked is a private key opened by a text candidate, with the
+// derivation path it sits at, if any.
+type unlocked struct {
+	key  key.Private
+	path string
+}
+
+// unlocker opens the private keys behind a text candidate such as a
+// mnemonic. Unlockers may keep state between calls, so every worker builds
+// its own.
+type unlocker func(secret string) ([]unlocked, error)
+
+// pickBackend returns the -backend deriving the candidates of ks. Walked
+// keyspaces add points instead of deriving keys, so auto does not time
+// the backends for them and returns nil.
+func pickBackend(name string, ks generator.Keyspace) (backend.Backend, error) {
+	if _, walks := ks.(generator.Layered); walks && name == "auto" {
+		return nil, nil
+	}
+	return backend.ByName(name)
+}
+
+// newWorker returns the runPool worker factory deriving and matching every
+// candidate of a batch. walk may be nil when no batch is a walk batch, and
+// newUnlocker when no batch carries texts.
+func newWorker(be backend.Backend, walk *walker.Walker, newUnlocker func() unlocker, m *matcher) func() func(batch) result {
+	return func() func(batch) result {
+		deriver := address.NewDeriver(be)
+		addrs := make([][address.Length]byte, batchSize)
+		var unlock unlocker
+		if newUnlocker != nil {
+			unlock = newUnlocker()
+		}
+
+		return func(b batch) result {
+			r := result{index: b.index, to: b.to, errs: b.errs}
+			if b.walk {
+				seen := uint64(0)
+				walk.Each(b.index, b.to, func(i uint64, priv *key.Private, addr *[20]byte, err error) bool {
+					seen++
+					if err != nil {
+						r.errs = append(r.errs, &key.IndexError{Index: i, Input: priv.Hex(), Err: err})
+						return true
+					}
+					m.check(&r, checkpoint.Hit{Private: priv.Hex()}, addr)
+					return true
+				})
+				r.skipped = b.to - b.index - seen
+				return r
+			}
+
+			for j, secret := range b.texts {
+				keys, err := unlock(secret)
+				if err != nil {
+					r.errs = append(r.errs, &unlockError{index: b.at[j], err: err})
+					continue
+				}
+				for _, k := range keys {
+					deriver.Derive((*[32]byte)(&k.key), &addrs[0])
+					m.check(&r, checkpoint.Hit{Private: k.key.Hex(), Path: k.path, Secret: secret}, &addrs[0])
+				}
+			}
+
+			if b.keys != nil {
+				deriver.DeriveBatch(b.keys, addrs)
+				for i := range b.keys {
+					m.check(&r, checkpoint.Hit{Private: b.keys[i].Hex()}, &addrs[i])
+				}
+				keyBuffers.Put(&b.keys)
+			}
+			return r
+		}
+	}
+}
+
+// unlockError is a password candidate failing other than by being wrong,
+// e.g. on a corrupt wallet. The message leaves the candidate out, as it
+// may be the password after all.
+type unlockError struct {
+	index uint64
+	err   error
+}
+
+func (e *unlockError) Error() string {
+	return fmt.Sprintf("candidate %d: unlock: %v", e.index, e.err)
+}
+
+func (e *unlockError) Unwrap() error {
+	return e.err
+}
+
+// feedWalk sends the pending ranges as walk batches.
+func feedWalk(pending []checkpoint.Range, done <-chan struct{}) func(func(batch) bool) {
+	return func(send func(batch) bool) {
+		send = guard(done, send)
+		for _, r := range pending {
+			for from := r.From; from < r.To; from += walkChunk {
+				if !send(batch{index: from, to: min(from+walkChunk, r.To), walk: true}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// feedKeyspace sends the pending ranges of ks as batches of decoded keys,
+// or of texts to unlock. Indexes that ks.Each skips are sent along as
+// tested.
+func feedKeyspace(ks generator.Keyspace, texts bool, pending []checkpoint.Range, done <-chan struct{}) func(func(batch) bool) {
+	return func(send func(batch) bool) {
+		bt := &batcher{send: guard(done, send), texts: texts, sparse: true}
+		for _, r := range pending {
+			ok := bt.seek(r.From)
+			ks.Each(r.From, r.To, func(i uint64, candidate string) bool {
+				ok = ok && bt.add(i, candidate)
+				return ok
+			})
+			if !ok || !bt.cover(r.To) {
+				return
+			}
+		}
+	}
+}
+
+// feedLines streams the pending lines of input as batches of decoded keys,
+// sending partial batches whenever the input stalls for flushIdle.
+func feedLines(input io.ReadCloser, pending []checkpoint.Range, done <-chan struct{}) func(func(batch) bool) {
+	return func(send func(batch) bool) {
+		defer input.Close()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		lines, errc := source.Stream(input, stop)
+
+		idle := time.NewTicker(flushIdle)
+		defer idle.Stop()
+
+		bt := &batcher{send: guard(done, send), lines: true}
+		next := 0
+		for {
+			select {
+			case l, ok := <-lines:
+				if !ok {
+					if err := <-errc; err != nil {
+						log.Println(err)
+					}
+					bt.flush()
+					return
+				}
+				for next < len(pending) && l.Index >= pending[next].To {
+					next++
+				}
+				if next == len(pending) {
+					bt.flush()
+					return
+				}
+				if l.Index >= pending[next].From && !bt.add(l.Index, l.Text) {
+					return
+				}
+			case <-idle.C:
+				if !bt.flush() {
+					return
+				}
+			case <-done:
+				// A stalled pipe or terminal must not hold up shutdown.
+				bt.flush()
+				return
+			}
+		}
+	}
+}
+
+// guard wraps send so that it refuses new batches once done is closed.
+func guard(done <-chan struct{}, send func(batch) bool) func(batch) bool {
+	return func(b batch) bool {
+		select {
+		case <-done:
+			return false
+		default:
+			return send(b)
+		}
+	}
+}
+
+// confirm checks that the key of a hit on a keystore or presale wallet
+// reproduces the address the file was saved for.
+func (f *candidateFlags) confirm(h checkpoint.Hit) {
+	switch {
+	case f.wallet != nil:
+		confirmAddress("keystore", f.wallet.Address, h)
+	case f.presaleWallet != nil:
+		confirmAddress("presale wallet", f.presaleWallet.Address, h)
+	}
+}
+
+func confirmAddress(file, want string, h checkpoint.Hit) {
+	addr, err := addressFromPrivate(h.Private)
+	switch {
+	case err != nil:
+		log.Printf("password %q decrypts an invalid key: %v", h.Secret, err)
+	case want == "":
+		log.Printf("password %q decrypts the key of 0x%s", h.Secret, addr)
+	case addr != want:
+		log.Printf("password %q decrypts the key of 0x%s, not of the %s address 0x%s", h.Secret, addr, file, want)
+	default:
+		log.Printf("password %q confirmed: its key reproduces the %s address 0x%s", h.Secret, file, addr)
+	}
+}
+
+// vaultKeys returns the keys of decrypted MetaMask keyrings: the accounts
+// of HD keyrings at their path, and imported keys.
+func vaultKeys(keyrings []metamask.Keyring) ([]unlocked, error) {
+	var keys []unlocked
+	for _, k := range keyrings {
+		for _, prv := range k.Keys {
+			keys = append(keys, unlocked{key: prv, path: "imported"})
+		}
+		if k.Mnemonic == "" {
+			continue
+		}
+		paths, err := hdwallet.Expand(k.HDPath+"/{i}", hdwallet.Range{}, hdwallet.Range{To: uint32(max(k.Accounts, 1) - 1)})
+		if err != nil {
+			return nil, err
+		}
+		hd, err := deriveAll(mnemonic.Seed(k.Mnemonic, ""), paths)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, hd...)
+	}
+	return keys, nil
+}
+
+// printHit writes h in the format of the header line, followed by the path,
+// secret and note of h when it has them.
+func printHit(w io.Writer, h checkpoint.Hit) {
+	line := h.Private + ";0x" + h.Address
+	for _, field := range []string{h.Path, h.Secret, h.Note} {
+		if field != "" {
+			line += ";" + field
+		}
+	}
+	fmt.Fprintln(w, line)
+}
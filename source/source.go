@@ -0,0 +1,144 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/seithhq/crypto-finder/encrypted"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Open opens a candidate list for streaming: a file, a named pipe or "-"
+// for stdin. gzip and zstd input is recognised by its magic bytes and
+// decompressed on the fly, and so is an encrypted file, decrypted with the
+// passphrase from the environment first.
+func Open(path string) (io.ReadCloser, error) {
+	var f io.ReadCloser = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+
+	r := bufio.NewReaderSize(f, 1<<16)
+	magic, _ := r.Peek(len(encrypted.Magic))
+	if !encrypted.Is(magic) {
+		return decompress(r, f)
+	}
+
+	passphrase, err := encrypted.Passphrase()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	dr, err := encrypted.NewReader(r, passphrase)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return decompress(bufio.NewReaderSize(dr, 1<<16), f)
+}
+
+func decompress(r *bufio.Reader, f io.Closer) (io.ReadCloser, error) {
+	magic, _ := r.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{gz, Closers{gz, f}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, Closers{zstdCloser{zr}, f}}, nil
+	}
+
+	return readCloser{r, f}, nil
+}
+
+// Lines calls fn with every line of r, trimmed of surrounding spaces, and
+// its zero-based index until r is exhausted or fn returns false.
+func Lines(r io.Reader, fn func(i uint64, line string) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+
+	for i := uint64(0); scanner.Scan(); i++ {
+		if !fn(i, strings.Trim(scanner.Text(), " \r")) {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+type Line struct {
+	Index uint64
+	Text  string
+}
+
+// Stream runs Lines in the background so the caller can react while the
+// input stalls, e.g. on a slow pipe. The lines channel is closed at the end
+// of r or once stop is closed; a read error is then sent on the error
+// channel.
+func Stream(r io.Reader, stop <-chan struct{}) (<-chan Line, <-chan error) {
+	lines := make(chan Line, 256)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+		errc <- Lines(r, func(i uint64, text string) bool {
+			select {
+			case lines <- Line{Index: i, Text: text}:
+				return true
+			case <-stop:
+				return false
+			}
+		})
+	}()
+
+	return lines, errc
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Closers closes each of its closers in order, returning the first error.
+type Closers []io.Closer
+
+func (c Closers) Close() error {
+	var first error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
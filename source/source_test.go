@@ -0,0 +1,161 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/seithhq/crypto-finder/encrypted"
+)
+
+// input has a blank line, padding and a CRLF ending, which Lines trims
+// but still counts, so indexes stay line numbers minus one.
+const input = "0a5c2dff\n\n  b9a6e124 \r\n0e7d8f58"
+
+var want = []string{"0a5c2dff", "", "b9a6e124", "0e7d8f58"}
+
+func gzipped(t *testing.T, plain []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, plain []byte) []byte {
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	return w.EncodeAll(plain, nil)
+}
+
+func sealed(t *testing.T, plain []byte) []byte {
+	var buf bytes.Buffer
+	w, err := encrypted.NewWriter(&buf, []byte("s3cret"), encrypted.KDF{Name: "scrypt", A: 10, B: 8, C: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv(encrypted.PassphraseEnv, "s3cret")
+
+	plain := []byte(input)
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"plain", plain},
+		{"gzip", gzipped(t, plain)},
+		{"zstd", zstded(t, plain)},
+		{"encrypted", sealed(t, plain)},
+		{"encrypted gzip", sealed(t, gzipped(t, plain))},
+		{"encrypted zstd", sealed(t, zstded(t, plain))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "candidates")
+			if err := os.WriteFile(path, tc.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			r, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			var got []string
+			err = Lines(r, func(i uint64, line string) bool {
+				if i != uint64(len(got)) {
+					t.Errorf("line %q has index %d, want %d", line, i, len(got))
+				}
+				got = append(got, line)
+				return true
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Lines = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOpenEncryptedNeedsPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "candidates")
+	if err := os.WriteFile(path, sealed(t, []byte(input)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(encrypted.PassphraseEnv, "")
+	if _, err := Open(path); !errors.Is(err, encrypted.ErrNoPassphrase) {
+		t.Errorf("no passphrase: %v, want %v", err, encrypted.ErrNoPassphrase)
+	}
+	// The first chunk, not the header, tells a wrong passphrase.
+	t.Setenv(encrypted.PassphraseEnv, "guess")
+	r, err := Open(path)
+	if err == nil {
+		defer r.Close()
+		err = Lines(r, func(uint64, string) bool { return true })
+	}
+	if !errors.Is(err, encrypted.ErrPassphrase) {
+		t.Errorf("wrong passphrase: %v, want %v", err, encrypted.ErrPassphrase)
+	}
+}
+
+func TestLinesStops(t *testing.T) {
+	n := 0
+	err := Lines(bytes.NewReader([]byte(input)), func(i uint64, line string) bool {
+		n++
+		return i < 1
+	})
+	if err != nil || n != 2 {
+		t.Errorf("Lines called fn %d times, %v; want 2", n, err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	lines, errc := Stream(bytes.NewReader([]byte(input)), make(chan struct{}))
+	var got []string
+	for l := range lines {
+		if l.Index != uint64(len(got)) {
+			t.Errorf("line %q has index %d, want %d", l.Text, l.Index, len(got))
+		}
+		got = append(got, l.Text)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream = %q, want %q", got, want)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	lines, errc = Stream(bytes.NewReader(bytes.Repeat([]byte("0a5c\n"), 1<<12)), stop)
+	n := 0
+	for range lines {
+		n++
+	}
+	if err := <-errc; err != nil || n == 1<<12 {
+		t.Errorf("stopped Stream sent %d of %d lines, %v", n, 1<<12, err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/seithhq/crypto-finder/encrypted"
+	"github.com/seithhq/crypto-finder/source"
+)
+
+// encryptFile encrypts a candidate list, checkpoint or hits file for
+// storage at rest. Every command reading such files decrypts it
+// transparently with the passphrase in $CRYPTO_FINDER_PASSPHRASE.
+func encryptFile(args []string) {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	kdfName := fs.String("kdf", "argon2id", "key derivation of the passphrase: argon2id or scrypt")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		log.Fatal("usage: encrypt [-kdf argon2id|scrypt] IN OUT, - for stdin or stdout")
+	}
+	kdf, err := encryption(*kdfName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	in, err := openStorage(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	out, err := createStorage(fs.Arg(1), kdf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// decryptFile restores the plaintext of an encrypted file, byte for byte:
+// a compressed candidate list stays compressed.
+func decryptFile(args []string) {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		log.Fatal("usage: decrypt IN OUT, - for stdin or stdout")
+	}
+	passphrase, err := encrypted.Passphrase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	in, err := openStorage(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	r, err := encrypted.NewReader(in, passphrase)
+	if err != nil {
+		log.Fatalf("%s: %v", fs.Arg(0), err)
+	}
+	out, err := createStorage(fs.Arg(1), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		log.Fatalf("%s: %v", fs.Arg(0), err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// encryption returns the KDF called name, or nil for no encryption. It
+// fails early when the passphrase is missing, rather than at the first
+// checkpoint.
+func encryption(name string) (*encrypted.KDF, error) {
+	if name == "" {
+		return nil, nil
+	}
+	kdf, err := encrypted.ParseKDF(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := encrypted.Passphrase(); err != nil {
+		return nil, err
+	}
+	return &kdf, nil
+}
+
+func openStorage(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+// storageWriter writes a file made by createStorage.
+type storageWriter struct {
+	io.Writer
+	io.Closer
+	enc *encrypted.Writer
+}
+
+// Flush makes sure what was written so far is in the file, sealed in its
+// own chunk when encrypted.
+func (w *storageWriter) Flush() error {
+	if w.enc == nil {
+		return nil
+	}
+	return w.enc.Flush()
+}
+
+// createStorage creates the file at path, - for stdout, refusing to
+// overwrite one. With a KDF, what is written is encrypted, and Close
+// finishes the encrypted file.
+func createStorage(path string, kdf *encrypted.KDF) (*storageWriter, error) {
+	var f io.WriteCloser = os.Stdout
+	if path != "-" {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+	if kdf == nil {
+		return &storageWriter{Writer: f, Closer: f}, nil
+	}
+
+	passphrase, err := encrypted.Passphrase()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w, err := encrypted.NewWriter(f, passphrase, *kdf)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &storageWriter{Writer: w, Closer: source.Closers{w, f}, enc: w}, nil
+}
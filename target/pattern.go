@@ -0,0 +1,64 @@
+package target
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/seithhq/crypto-finder/generator"
+)
+
+// Pattern is a partially known address written in the generator mask
+// syntax, e.g. "0x12ab34...cdef" or "12ab34*c[d-f]ef".
+type Pattern struct {
+	Source string
+	Bits   float64
+	mask   *generator.Mask
+}
+
+// IsPattern reports whether s is meant as a pattern rather than a complete
+// address.
+func IsPattern(s string) bool {
+	return strings.ContainsAny(s, "?*[{.")
+}
+
+// Split splits a comma-separated list of addresses and patterns, leaving
+// the commas of {m,n} quantifiers alone.
+func Split(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '{':
+			depth++
+		case '}':
+			depth = max(depth-1, 0)
+		case ',':
+			if depth == 0 {
+				parts = append(parts, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, list[start:])
+}
+
+func ParsePattern(s string) (*Pattern, error) {
+	mask, err := generator.CompileMask(s, 2*AddressLength)
+	if err != nil {
+		return nil, fmt.Errorf("target %q: %w", s, err)
+	}
+
+	return &Pattern{Source: s, Bits: mask.Bits(), mask: mask}, nil
+}
+
+// Match reports whether the lowercase hex address fits the pattern.
+func (p *Pattern) Match(address string) bool {
+	return p.mask.Match(address)
+}
+
+// FalsePositives estimates how many of tested random candidates would match
+// the pattern by chance.
+func (p *Pattern) FalsePositives(tested uint64) float64 {
+	return float64(tested) * math.Exp2(-p.Bits)
+}
@@ -0,0 +1,64 @@
+package target
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	for _, tc := range []struct {
+		list string
+		want []string
+	}{
+		{"0x5beca33b3f3a6d37a422359aca8d2ab77d4065df", []string{"0x5beca33b3f3a6d37a422359aca8d2ab77d4065df"}},
+		{"5bec*65df,12ab*cdef", []string{"5bec*65df", "12ab*cdef"}},
+		{"5bec?{1,3}*65df", []string{"5bec?{1,3}*65df"}},
+		{"5bec?{1,3}*65df,12ab[0-3]{2}*", []string{"5bec?{1,3}*65df", "12ab[0-3]{2}*"}},
+		{"a,,b", []string{"a", "", "b"}},
+	} {
+		if got := Split(tc.list); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Split(%q) = %q, want %q", tc.list, got, tc.want)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	const addr = "5beca33b3f3a6d37a422359aca8d2ab77d4065df"
+	for _, tc := range []struct {
+		pattern string
+		match   bool
+		bits    float64
+	}{
+		{"0x5beca33b...4065df", true, 56},
+		{"5BECA33B*4065DF", true, 56},
+		// The layouts of a quantifier each match, lowering the bits.
+		{"5bec?{1,3}*65df", true, 30.4},
+		{"5bec[a-c]*65df", true, 34.4},
+		{"5bec*65de", false, 32},
+		{"????????????????????????????????????????", true, 0},
+	} {
+		p, err := ParsePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("ParsePattern(%q): %v", tc.pattern, err)
+			continue
+		}
+		if p.Match(addr) != tc.match {
+			t.Errorf("%q matches %s: %t, want %t", tc.pattern, addr, !tc.match, tc.match)
+		}
+		if p.Bits < tc.bits-0.1 || p.Bits > tc.bits+0.1 {
+			t.Errorf("%q has %.1f bits, want about %g", tc.pattern, p.Bits, tc.bits)
+		}
+		if !IsPattern(tc.pattern) {
+			t.Errorf("IsPattern(%q) = false", tc.pattern)
+		}
+	}
+
+	for _, bad := range []string{"5bec?{1", "5bec[g]*", "5bec*65df*00*" + addr} {
+		if _, err := ParsePattern(bad); err == nil {
+			t.Errorf("ParsePattern(%q) succeeds", bad)
+		}
+	}
+	if IsPattern("0x" + addr) {
+		t.Errorf("IsPattern(%q) = true", addr)
+	}
+}
@@ -0,0 +1,87 @@
+package target
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const AddressLength = 20
+
+type Address [AddressLength]byte
+
+// ParseAddress decodes a hex address. The 0x prefix and EIP-55 casing are
+// optional.
+func ParseAddress(s string) (Address, error) {
+	var addr Address
+
+	raw := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	if len(raw) != 2*AddressLength {
+		return addr, fmt.Errorf("target %q: want %d hex characters, got %d", s, 2*AddressLength, len(raw))
+	}
+	if _, err := hex.Decode(addr[:], []byte(raw)); err != nil {
+		return addr, fmt.Errorf("target %q: %w", s, err)
+	}
+
+	return addr, nil
+}
+
+// Set holds the addresses of the wallet being recovered and remembers which
+// of them have already been hit. The address index is never written after
+// NewSet, so Contains is safe to call from every worker without locking.
+type Set struct {
+	index map[Address]int
+
+	mu      sync.Mutex
+	found   []bool
+	missing int
+}
+
+func NewSet(addresses []string) (*Set, error) {
+	s := &Set{index: make(map[Address]int, len(addresses))}
+	for _, raw := range addresses {
+		addr, err := ParseAddress(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := s.index[addr]; !ok {
+			s.index[addr] = len(s.found)
+			s.found = append(s.found, false)
+		}
+	}
+	s.missing = len(s.found)
+
+	return s, nil
+}
+
+func (s *Set) Len() int {
+	return len(s.index)
+}
+
+// Contains reports whether addr is one of the targets.
+func (s *Set) Contains(addr Address) bool {
+	_, ok := s.index[addr]
+	return ok
+}
+
+// Hit marks addr as found and reports whether every target has been found.
+func (s *Set) Hit(addr Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i, ok := s.index[addr]; ok && !s.found[i] {
+		s.found[i] = true
+		s.missing--
+	}
+
+	return s.missing == 0
+}
+
+// Done reports whether there are targets and all of them have been found.
+func (s *Set) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.found) > 0 && s.missing == 0
+}
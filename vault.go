@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/seithhq/crypto-finder/checkpoint"
+	"github.com/seithhq/crypto-finder/metamask"
+)
+
+// openVault decrypts a MetaMask vault with a known password and lists its
+// keyrings: the seed phrase of every HD keyring and every key with its
+// address. Without -password, the password is read from stdin so that it
+// stays out of the shell history.
+func openVault(args []string) {
+	fs := flag.NewFlagSet("vault", flag.ExitOnError)
+	var opts candidateFlags
+	fs.StringVar(&opts.vault, "vault", "", "MetaMask vault: JSON with data, iv and salt")
+	password := fs.String("password", "", "vault password, read from stdin when empty")
+	fs.Parse(args)
+
+	if opts.vault == "" && fs.NArg() == 1 {
+		opts.vault = fs.Arg(0)
+	}
+	if opts.vault == "" {
+		log.Fatal("vault needs -vault")
+	}
+	vault, err := opts.openVault()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyrings, err := vault.Unlock(readPassword(*password))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for n, k := range keyrings {
+		fmt.Printf("keyring %d: %s\n", n+1, k.Type)
+		if k.Mnemonic != "" {
+			fmt.Printf("mnemonic %s\n", k.Mnemonic)
+			fmt.Printf("hd_path %s, %d accounts\n", k.HDPath, k.Accounts)
+		}
+		keys, err := vaultKeys([]metamask.Keyring{k})
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, u := range keys {
+			printHit(os.Stdout, checkpoint.Hit{Private: u.key.Hex(), Address: addressFromKey(u.key), Path: u.path})
+		}
+	}
+}
+
+// readPassword returns password, or when it is empty a line of stdin.
+func readPassword(password string) string {
+	if password != "" {
+		return password
+	}
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatal(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
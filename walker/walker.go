@@ -0,0 +1,266 @@
+package walker
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/seithhq/crypto-finder/address"
+	"github.com/seithhq/crypto-finder/backend"
+	"github.com/seithhq/crypto-finder/generator"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+// BlockSize is how many points share one field inversion when converting
+// to affine coordinates.
+const BlockSize = 256
+
+// Walker enumerates a mask keyspace without a scalar multiplication per
+// candidate. Consecutive candidates of a layout differ by a known scalar
+// delta, so the next public key is the previous one plus a precomputed
+// multiple of G, and blocks of points are converted to affine coordinates
+// with a single Montgomery batch inversion.
+//
+// A Walker is safe for concurrent use; the delta tables are built lazily
+// and shared.
+type Walker struct {
+	layouts generator.Layered
+
+	mu    sync.Mutex
+	steps map[uint64]*layoutSteps
+	cache map[[32]byte]secp256k1.JacobianPoint
+}
+
+// maxSteps and maxPoints bound the delta tables and points kept for
+// reuse. A ranked keyspace can have far more layouts than a mask, and the
+// search moves on from each of them, so both caches are dropped when full.
+const (
+	maxSteps  = 4096
+	maxPoints = 1 << 16
+)
+
+// layoutSteps builds the steps of a layout once, keyed by its first index.
+type layoutSteps struct {
+	once  sync.Once
+	steps *steps
+}
+
+// steps holds the deltas of one layout. free lists the positions that vary,
+// fastest first, and deltas[k][a] is the public-key delta of moving free[k]
+// from its a-th to its a+1-th character while every faster position wraps
+// from its last character back to its first.
+type steps struct {
+	free   []int
+	deltas [][]secp256k1.JacobianPoint
+}
+
+func New(ks generator.Layered) *Walker {
+	return &Walker{
+		layouts: ks,
+		steps:   make(map[uint64]*layoutSteps),
+		cache:   make(map[[32]byte]secp256k1.JacobianPoint),
+	}
+}
+
+func (w *Walker) layoutSteps(layout generator.Layout) *steps {
+	w.mu.Lock()
+	ls, ok := w.steps[layout.First]
+	if !ok {
+		if len(w.steps) == maxSteps {
+			clear(w.steps)
+		}
+		ls = &layoutSteps{}
+		w.steps[layout.First] = ls
+	}
+	w.mu.Unlock()
+
+	ls.once.Do(func() {
+		sets := layout.Sets
+		s := &steps{}
+		for p := len(sets) - 1; p >= 0; p-- {
+			if len(sets[p]) > 1 {
+				s.free = append(s.free, p)
+			}
+		}
+
+		var wrap secp256k1.ModNScalar
+		for _, p := range s.free {
+			set := sets[p]
+			deltas := make([]secp256k1.JacobianPoint, len(set)-1)
+			for a := range deltas {
+				d := nibbleScalar(p, nibble(set[a+1])-nibble(set[a]))
+				d.Add(&wrap)
+				deltas[a] = w.point(&d)
+			}
+			s.deltas = append(s.deltas, deltas)
+
+			back := nibbleScalar(p, nibble(set[0])-nibble(set[len(set)-1]))
+			wrap.Add(&back)
+		}
+		ls.steps = s
+	})
+	return ls.steps
+}
+
+// point returns d*G in affine coordinates, reusing deltas shared between
+// layouts.
+func (w *Walker) point(d *secp256k1.ModNScalar) secp256k1.JacobianPoint {
+	b := d.Bytes()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p, ok := w.cache[b]
+	if !ok {
+		if len(w.cache) == maxPoints {
+			clear(w.cache)
+		}
+		secp256k1.ScalarBaseMultNonConst(d, &p)
+		p.ToAffine()
+		w.cache[b] = p
+	}
+	return p
+}
+
+// Each calls fn with the private key and address of the candidates at
+// [from, to). Keys that are not valid scalars come with their validation
+// error and a zero address. Like the keyspace's Each it skips candidates
+// an earlier layout already yielded. The pointers are only valid during
+// the call.
+func (w *Walker) Each(from, to uint64, fn func(i uint64, priv *key.Private, addr *[20]byte, err error) bool) {
+	b := newBlock()
+	w.layouts.EachLayout(from, to, func(layout generator.Layout) bool {
+		lo, hi := max(from, layout.First), min(to, layout.First+layout.Size)
+		return b.walk(layout, w.layoutSteps(layout), lo, hi, fn)
+	})
+}
+
+type block struct {
+	privs   [BlockSize]key.Private
+	points  [BlockSize]secp256k1.JacobianPoint
+	acc     [BlockSize]secp256k1.FieldVal
+	addr    [address.Length]byte
+	hex     [generator.KeyLength]byte
+	deriver *address.Deriver
+}
+
+func newBlock() *block {
+	return &block{deriver: address.NewDeriver(nil)}
+}
+
+func (b *block) walk(layout generator.Layout, s *steps, lo, hi uint64, fn func(uint64, *key.Private, *[20]byte, error) bool) bool {
+	digits := make([]int, len(layout.Sets))
+	var priv key.Private
+	rest := lo - layout.First
+	for p := len(layout.Sets) - 1; p >= 0; p-- {
+		n := uint64(len(layout.Sets[p]))
+		digits[p] = int(rest % n)
+		rest /= n
+		setNibble(&priv, p, nibble(layout.Sets[p][digits[p]]))
+	}
+
+	var scalar secp256k1.ModNScalar
+	var point secp256k1.JacobianPoint
+	scalar.SetBytes((*[32]byte)(&priv))
+	secp256k1.ScalarBaseMultNonConst(&scalar, &point)
+
+	for i := lo; i < hi; {
+		n := 0
+		for ; n < BlockSize && i+uint64(n) < hi; n++ {
+			b.privs[n] = priv
+			b.points[n] = point
+			if i+uint64(n)+1 == hi {
+				n++
+				break
+			}
+			s.advance(layout.Sets, digits, &priv, &point)
+		}
+
+		if !b.flush(&layout, i, n, fn) {
+			return false
+		}
+		i += uint64(n)
+	}
+	return true
+}
+
+// advance moves priv and point to the next candidate of the layout.
+func (s *steps) advance(sets []string, digits []int, priv *key.Private, point *secp256k1.JacobianPoint) {
+	for k, p := range s.free {
+		set := sets[p]
+		if digits[p]+1 < len(set) {
+			digits[p]++
+			setNibble(priv, p, nibble(set[digits[p]]))
+			var next secp256k1.JacobianPoint
+			secp256k1.AddNonConst(point, &s.deltas[k][digits[p]-1], &next)
+			*point = next
+			return
+		}
+		digits[p] = 0
+		setNibble(priv, p, nibble(set[0]))
+	}
+}
+
+// flush converts the first n points to affine coordinates with one
+// inversion and hands every candidate of layout that is not a duplicate to
+// fn.
+func (b *block) flush(layout *generator.Layout, first uint64, n int, fn func(uint64, *key.Private, *[20]byte, error) bool) bool {
+	backend.ToAffine(b.points[:n], b.acc[:n])
+
+	overlapped := layout.Overlapped()
+	for j := 0; j < n; j++ {
+		if overlapped {
+			if hex.Encode(b.hex[:], b.privs[j][:]); layout.Duplicate(b.hex[:]) {
+				continue
+			}
+		}
+
+		if err := b.privs[j].Validate(); err != nil || b.points[j].Z.IsZero() {
+			if err == nil {
+				err = key.ErrZero
+			}
+			b.addr = [20]byte{}
+			if !fn(first+uint64(j), &b.privs[j], &b.addr, err) {
+				return false
+			}
+			continue
+		}
+
+		b.deriver.FromAffine(&b.points[j].X, &b.points[j].Y, &b.addr)
+		if !fn(first+uint64(j), &b.privs[j], &b.addr, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func nibble(c byte) int {
+	return strings.IndexByte(generator.HexChars, c)
+}
+
+// nibbleScalar returns d * 16^(63-p), the value of digit d at key position
+// p, reduced mod N.
+func nibbleScalar(p, d int) secp256k1.ModNScalar {
+	var b key.Private
+	abs := d
+	if d < 0 {
+		abs = -d
+	}
+	setNibble(&b, p, abs)
+
+	var s secp256k1.ModNScalar
+	s.SetBytes((*[32]byte)(&b))
+	if d < 0 {
+		s.Negate()
+	}
+	return s
+}
+
+func setNibble(k *key.Private, p, v int) {
+	if p%2 == 0 {
+		k[p/2] = k[p/2]&0x0f | byte(v)<<4
+	} else {
+		k[p/2] = k[p/2]&0xf0 | byte(v)
+	}
+}
@@ -0,0 +1,152 @@
+package walker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/seithhq/crypto-finder/generator"
+	"github.com/seithhq/crypto-finder/key"
+)
+
+const fragment = "0a5c2dffb9a6e1240e7d8f58b1e68d6c9fce1e6e9b0a5c0e7f1b2c3a4b5"
+
+// overlapping has four layouts, of gaps 0+3, 1+2, 2+1 and 3+0, that share
+// every candidate with a 0 in the right place.
+var overlapping = fragment[:59] + "[0-7]*0*"
+
+func mustMask(t testing.TB, src string) *generator.Mask {
+	m, err := generator.ParseMask(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// walkRange checks the walker against the keyspace and go-ethereum on the
+// candidates at [from, to).
+func walkRange(t *testing.T, m generator.Layered, w *Walker, from, to uint64) {
+	var want []uint64
+	keys := map[uint64]string{}
+	m.Each(from, to, func(i uint64, k string) bool {
+		want = append(want, i)
+		keys[i] = k
+		return true
+	})
+
+	var got []uint64
+	w.Each(from, to, func(i uint64, priv *key.Private, addr *[20]byte, err error) bool {
+		got = append(got, i)
+		if priv.Hex() != keys[i] {
+			t.Fatalf("candidate %d: walker key %s, mask key %s", i, priv.Hex(), keys[i])
+		}
+		ecdsa, wantErr := crypto.ToECDSA(priv[:])
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("candidate %d: walker error %v, go-ethereum error %v", i, err, wantErr)
+		}
+		if err == nil && *addr != crypto.PubkeyToAddress(ecdsa.PublicKey) {
+			t.Fatalf("candidate %d: walker address %x, want %x", i, addr[:], crypto.PubkeyToAddress(ecdsa.PublicKey))
+		}
+		return true
+	})
+
+	if len(got) != len(want) {
+		t.Fatalf("[%d, %d): walker yields %d candidates, mask %d", from, to, len(got), len(want))
+	}
+	for j := range got {
+		if got[j] != want[j] {
+			t.Fatalf("[%d, %d): walker yields index %d, mask %d", from, to, got[j], want[j])
+		}
+	}
+}
+
+func TestWalkAcrossLayouts(t *testing.T) {
+	m := mustMask(t, overlapping)
+	layouts := m.Layouts()
+	if dups, _ := m.Duplicates(); len(layouts) != 4 || dups == 0 {
+		t.Fatalf("%d layouts sharing %d candidates, want 4 overlapping ones", len(layouts), dups)
+	}
+	w := New(m)
+
+	// Start mid-block and mid-layout, and cross every layout boundary.
+	walkRange(t, m, w, 1000, 31000)
+	for _, l := range layouts[1:] {
+		walkRange(t, m, w, l.First-BlockSize-5, l.First+2*BlockSize+3)
+	}
+	walkRange(t, m, w, m.Size()-300, m.Size())
+}
+
+func TestWalkRanked(t *testing.T) {
+	m := mustMask(t, overlapping)
+	r, err := m.Rank(generator.Confidence{
+		Prefer:    map[int]generator.Preference{61: {Chars: "0", Chance: 0.6}, 62: {Chars: "12", Chance: 0.8}, 64: {Chars: "0", Chance: 0.7}},
+		MissingAt: "end",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := New(r)
+
+	// Ranked parts are small, so these ranges cross many of them.
+	walkRange(t, r, w, 0, r.Size())
+	walkRange(t, r, w, 777, 9000)
+}
+
+func TestWalkWholeMask(t *testing.T) {
+	m := mustMask(t, overlapping)
+	w := New(m)
+
+	var want []string
+	m.Each(0, m.Size(), func(_ uint64, k string) bool {
+		want = append(want, k)
+		return true
+	})
+	n := 0
+	w.Each(0, m.Size(), func(i uint64, priv *key.Private, _ *[20]byte, _ error) bool {
+		if n >= len(want) || priv.Hex() != want[n] {
+			t.Fatalf("candidate %d: walker key %s out of step with the mask", i, priv.Hex())
+		}
+		n++
+		return true
+	})
+	if n != len(want) {
+		t.Fatalf("walker yields %d candidates, mask %d", n, len(want))
+	}
+}
+
+func TestWalkInvalidKeys(t *testing.T) {
+	for _, src := range []string{
+		strings.Repeat("0", 62) + "??",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd03641??",
+	} {
+		m := mustMask(t, src)
+		walkRange(t, m, New(m), 0, m.Size())
+	}
+}
+
+func TestWalkStopsEarly(t *testing.T) {
+	m := mustMask(t, overlapping)
+	calls := 0
+	New(m).Each(5, m.Size(), func(uint64, *key.Private, *[20]byte, error) bool {
+		calls++
+		return calls < 10
+	})
+	if calls != 10 {
+		t.Fatalf("%d calls after fn returned false, want 10", calls)
+	}
+}
+
+func BenchmarkWalk(b *testing.B) {
+	m := mustMask(b, fragment[:58]+"??????")
+	w := New(m)
+	// Build the delta tables outside the timing.
+	w.Each(0, 1, func(uint64, *key.Private, *[20]byte, error) bool { return true })
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for left := uint64(b.N); left > 0; {
+		n := min(left, m.Size())
+		w.Each(0, n, func(uint64, *key.Private, *[20]byte, error) bool { return true })
+		left -= n
+	}
+}